@@ -0,0 +1,2 @@
+-- Every service owns its database, spreadword_db (POSTGRES_DB) belongs to user_service
+CREATE DATABASE post_db;
@@ -1,46 +1,77 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	amqpparams "photo_service/internal/amqp_params"
 	deliveryhandler "photo_service/internal/delivery_handler"
-	"photo_service/internal/repository"
+	"photo_service/internal/worker"
+	"runtime"
+	"shared/eventbus"
+	"shared/rabbitmq"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
-	amqpParams := amqpparams.New()
-	msgs, err := amqpParams.Channel.Consume(
-		amqpParams.Queue.Name,
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if err != nil {
-		logrus.Fatalln(err)
+	// `main upgrade-queues` redeclares the work queues left by older releases and exits
+	if len(os.Args) > 1 && os.Args[1] == "upgrade-queues" {
+		upgraded, err := rabbitmq.UpgradePhotoQueues(amqpparams.URL)
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		logrus.Infof("upgraded queues: %v", upgraded)
+		return
 	}
-	repo := repository.New()
 
-	// Starting listener goroutine
-	logrus.Info("application is being launched!")
-	go func() {
-		for d := range msgs {
-			deliveryhandler.Handle(d, repo)
+	// Workers handle deliveries concurrently, the prefetch bounds how many
+	// unacked deliveries wait for them
+	workers := intFromEnv("WORKERS", runtime.NumCPU())
+	prefetch := intFromEnv("PREFETCH", 2*workers)
+	amqpParams := amqpparams.New(prefetch)
+	pool := worker.New(amqpParams, workers)
+	handler := deliveryhandler.Handler{Publisher: amqpParams}
+	consumers := []struct {
+		queue   string
+		handler eventbus.Handler
+	}{
+		{amqpparams.PhotoQueue, handler.Handle},
+		{amqpparams.AvatarProcessQueue, handler.HandleAvatar},
+		{amqpparams.ImageProcessQueue, handler.HandleImage},
+	}
+	for _, consumer := range consumers {
+		if err := pool.Subscribe(consumer.queue, consumer.handler); err != nil {
+			logrus.Fatalln(err)
 		}
-	}()
+	}
+	pool.Start()
+	logrus.WithFields(logrus.Fields{"workers": pool.Workers, "prefetch": prefetch}).
+		Info("application is being launched!")
 
 	// Graceful Shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 	stoppingSignal := <-stop
-	logrus.WithField("signal", stoppingSignal).Info("application has been stopped")
+	logrus.WithField("signal", stoppingSignal).Info("stopping application")
+
+	shutdownCtx, shutdownRelease := context.WithTimeout(context.Background(), 30*time.Second)
+	defer shutdownRelease()
+	if err := pool.Shutdown(shutdownCtx); err != nil {
+		logrus.Errorln(err)
+	}
+	amqpParams.Close()
+
+	logrus.Info("application has been stopped")
+}
 
-	amqpParams.Channel.Close()
-	amqpParams.Conn.Close()
+func intFromEnv(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 1 {
+		return fallback
+	}
+	return value
 }
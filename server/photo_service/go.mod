@@ -2,10 +2,12 @@ module photo_service
 
 go 1.21.6
 
+require shared v0.0.0
+
+replace shared => ../shared
+
 require (
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
-	github.com/jmoiron/sqlx v1.4.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
 	github.com/rabbitmq/amqp091-go v1.10.0 // indirect
 	github.com/sirupsen/logrus v1.9.3 // indirect
 	golang.org/x/image v0.17.0 // indirect
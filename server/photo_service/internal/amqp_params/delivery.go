@@ -0,0 +1,91 @@
+package amqpparams
+
+import (
+	"context"
+	"shared/eventbus"
+	"shared/rabbitmq"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+// retryCountHeader counts how many times a message went through the retry queues
+const retryCountHeader = "x-retry-count"
+
+// Dispatch runs handler on the envelope of a delivery of queue and settles it
+// with the outcome, see rabbitmq.Handle
+func (p *AmqpParams) Dispatch(queue string, d amqp.Delivery, handler eventbus.Handler) {
+	p.Settle(queue, d, rabbitmq.Handle(d, handler))
+}
+
+// Settle acknowledges a delivery of queue according to the result of handling it:
+// successful deliveries are acked, transient failures are retried after the next
+// of rabbitmq.PhotoRetryDelays and messages that failed permanently (see eventbus.Permanent)
+// or ran out of retries are dead-lettered
+func (p *AmqpParams) Settle(queue string, d amqp.Delivery, err error) {
+	log := logrus.WithFields(logrus.Fields{"queue": queue, "messageId": d.MessageId})
+	if err == nil {
+		if ackErr := d.Ack(false); ackErr != nil {
+			log.Errorln(ackErr)
+		}
+		return
+	}
+
+	attempt := retryCount(d) + 1
+	if eventbus.IsPermanent(err) || attempt > len(rabbitmq.PhotoRetryDelays) {
+		log.WithField("attempts", attempt).Errorln("dead-lettering message: ", err)
+		if nackErr := d.Nack(false, false); nackErr != nil {
+			log.Errorln(nackErr)
+		}
+		return
+	}
+
+	log.WithField("attempt", attempt).Warnln("retrying message: ", err)
+	if retryErr := p.retry(queue, d, attempt); retryErr != nil {
+		// Put it back on the queue rather than lose it
+		log.Errorln(retryErr)
+		if nackErr := d.Nack(false, true); nackErr != nil {
+			log.Errorln(nackErr)
+		}
+		return
+	}
+	if ackErr := d.Ack(false); ackErr != nil {
+		log.Errorln(ackErr)
+	}
+}
+
+func (p *AmqpParams) retry(queue string, d amqp.Delivery, attempt int) error {
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	headers[retryCountHeader] = int32(attempt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return p.conn.Publish(ctx,
+		"",
+		rabbitmq.RetryQueueName(queue, attempt),
+		amqp.Publishing{
+			Headers:      headers,
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    d.MessageId,
+			Type:         d.Type,
+			Body:         d.Body,
+		},
+	)
+}
+
+func retryCount(d amqp.Delivery) int {
+	switch count := d.Headers[retryCountHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	}
+	return 0
+}
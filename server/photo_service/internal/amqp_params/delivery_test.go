@@ -0,0 +1,123 @@
+package amqpparams
+
+import (
+	"context"
+	"errors"
+	"shared/eventbus"
+	"shared/rabbitmq"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// acknowledger records how a delivery was settled
+type acknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+type ping struct{}
+
+func (ping) EventType() string { return "ping" }
+func (ping) EventVersion() int { return 1 }
+
+// TestDispatch covers the outcomes that don't go through a retry queue, which needs a broker
+func TestDispatch(t *testing.T) {
+	envelope, err := eventbus.NewEnvelope("test", &ping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := envelope.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transient := func(context.Context, *eventbus.Envelope) error { return errors.New("timeout") }
+
+	tests := []struct {
+		name    string
+		body    []byte
+		retries int32
+		handler eventbus.Handler
+		want    acknowledger
+	}{
+		{
+			name:    "acks handled deliveries",
+			body:    body,
+			handler: func(context.Context, *eventbus.Envelope) error { return nil },
+			want:    acknowledger{acked: true},
+		},
+		{
+			name:    "dead-letters deliveries that aren't envelopes",
+			body:    []byte("not json"),
+			handler: func(context.Context, *eventbus.Envelope) error { return nil },
+			want:    acknowledger{nacked: true},
+		},
+		{
+			name: "dead-letters permanent failures",
+			body: body,
+			handler: func(context.Context, *eventbus.Envelope) error {
+				return eventbus.Permanent(errors.New("malformed"))
+			},
+			want: acknowledger{nacked: true},
+		},
+		{
+			name:    "dead-letters panicking handlers",
+			body:    body,
+			handler: func(context.Context, *eventbus.Envelope) error { panic("boom") },
+			want:    acknowledger{nacked: true},
+		},
+		{
+			name:    "dead-letters messages out of retries",
+			body:    body,
+			retries: int32(len(rabbitmq.PhotoRetryDelays)),
+			handler: transient,
+			want:    acknowledger{nacked: true},
+		},
+	}
+	p := &AmqpParams{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := &acknowledger{}
+			d := amqp.Delivery{
+				Acknowledger: got,
+				DeliveryTag:  1,
+				Headers:      amqp.Table{retryCountHeader: test.retries},
+				Body:         test.body,
+			}
+			p.Dispatch(PhotoQueue, d, test.handler)
+			if *got != test.want {
+				t.Errorf("settled as %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryCount(t *testing.T) {
+	tests := []struct {
+		headers amqp.Table
+		want    int
+	}{
+		{nil, 0},
+		{amqp.Table{retryCountHeader: int32(2)}, 2},
+		{amqp.Table{retryCountHeader: int64(3)}, 3},
+		{amqp.Table{retryCountHeader: "1"}, 0},
+	}
+	for _, test := range tests {
+		if got := retryCount(amqp.Delivery{Headers: test.headers}); got != test.want {
+			t.Errorf("retryCount(%v) = %d, want %d", test.headers, got, test.want)
+		}
+	}
+}
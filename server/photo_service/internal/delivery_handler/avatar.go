@@ -0,0 +1,94 @@
+package deliveryhandler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	amqpparams "photo_service/internal/amqp_params"
+	"shared/eventbus"
+	"shared/photo"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// AvatarSizes are the square sizes in pixels every avatar is rendered in
+var AvatarSizes = []int{32, 64, 200, 512}
+
+const maxAvatarSize = 512
+
+// HandleAvatar normalizes an avatar uploaded by a user: the image (PNG, JPEG,
+// GIF first frame or WebP) is center cropped to a square and rendered in AvatarSizes
+func (h *Handler) HandleAvatar(ctx context.Context, envelope *eventbus.Envelope) error {
+	message := photo.ProcessAvatarMessage{}
+	err := envelope.Decode(&message)
+	if err != nil {
+		return eventbus.Permanent(err)
+	}
+
+	source, err := decodeUpload(message.Data)
+	if err != nil {
+		// The upload was validated by user_service, it's not worth retrying
+		return eventbus.Permanent(fmt.Errorf("avatar of user %d: %w", message.UserId, err))
+	}
+	avatars, err := renderAvatarSizes(cropSquare(source))
+	if err != nil {
+		return err
+	}
+	return h.publishAvatars(ctx, message.UserId, avatars)
+}
+
+// publishAvatars sends rendered avatars back to user_service, which owns them
+func (h *Handler) publishAvatars(ctx context.Context, userId int, avatars map[int][]byte) error {
+	envelope, err := eventbus.NewEnvelope(eventSource, &photo.AvatarGeneratedMessage{UserId: userId, Avatars: avatars})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return h.Publisher.Publish(ctx, amqpparams.AvatarResultQueue, envelope)
+}
+
+// decodeUpload decodes an uploaded image after checking its dimensions,
+// so a small file can't make us allocate a huge canvas
+func decodeUpload(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("invalid image dimensions %dx%d", config.Width, config.Height)
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	return source, nil
+}
+
+// cropSquare cuts the largest centered square out of the image
+func cropSquare(source image.Image) image.Image {
+	bounds := source.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), source, image.Point{X: x, Y: y}, draw.Src)
+	return square
+}
+
+// renderAvatarSizes scales a square image into every avatar size, encoded as PNG
+func renderAvatarSizes(square image.Image) (map[int][]byte, error) {
+	avatars := make(map[int][]byte, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		target := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.CatmullRom.Scale(target, target.Bounds(), square, square.Bounds(), draw.Src, nil)
+		data, err := encodePNG(target)
+		if err != nil {
+			return nil, err
+		}
+		avatars[size] = data
+	}
+	return avatars, nil
+}
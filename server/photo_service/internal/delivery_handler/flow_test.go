@@ -0,0 +1,101 @@
+package deliveryhandler
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	amqpparams "photo_service/internal/amqp_params"
+	"shared/eventbus"
+	"shared/photo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestAvatarFlow plays user_service against the handlers of photo_service with the
+// messages both of them use
+func TestAvatarFlow(t *testing.T) {
+	bus := eventbus.NewMemory()
+	defer bus.Close()
+
+	handler := Handler{Publisher: bus}
+	for queue, handle := range map[string]eventbus.Handler{
+		amqpparams.PhotoQueue:         handler.Handle,
+		amqpparams.AvatarProcessQueue: handler.HandleAvatar,
+	} {
+		if err := bus.Subscribe(queue, handle); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	generated := map[int]map[int][]byte{}
+	err := bus.Subscribe(amqpparams.AvatarResultQueue, func(ctx context.Context, envelope *eventbus.Envelope) error {
+		var message photo.AvatarGeneratedMessage
+		if err := envelope.Decode(&message); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		generated[message.UserId] = message.Avatars
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var upload bytes.Buffer
+	source := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	source.Set(10, 10, color.RGBA{R: 255, A: 255})
+	if err = png.Encode(&upload, source); err != nil {
+		t.Fatal(err)
+	}
+	messages := []struct {
+		queue string
+		event eventbus.Event
+	}{
+		{amqpparams.PhotoQueue, &photo.CreatePhotoMessage{UserId: 1, Username: "johnny"}},
+		{amqpparams.AvatarProcessQueue, &photo.ProcessAvatarMessage{UserId: 2, Data: upload.Bytes()}},
+		{amqpparams.AvatarProcessQueue, &photo.ProcessAvatarMessage{UserId: 3, Data: []byte("not an image")}},
+	}
+	for _, message := range messages {
+		envelope, err := eventbus.NewEnvelope("user_service", message.event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = bus.Publish(context.Background(), message.queue, envelope); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = bus.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	for _, userId := range []int{1, 2} {
+		avatars, ok := generated[userId]
+		if !ok {
+			t.Fatalf("no avatar generated for user %d", userId)
+		}
+		for _, size := range AvatarSizes {
+			config, err := png.DecodeConfig(bytes.NewReader(avatars[size]))
+			if err != nil {
+				t.Fatalf("avatar %d of user %d: %v", size, userId, err)
+			}
+			if config.Width != size || config.Height != size {
+				t.Errorf("avatar %d of user %d is %dx%d", size, userId, config.Width, config.Height)
+			}
+		}
+	}
+	if _, ok := generated[3]; ok {
+		t.Error("generated an avatar from an invalid upload")
+	}
+	failures := bus.Failures()
+	if len(failures) != 1 || failures[0].Queue != amqpparams.AvatarProcessQueue || !eventbus.IsPermanent(failures[0].Err) {
+		t.Fatalf("Failures() = %v, want the invalid upload to fail permanently", failures)
+	}
+}
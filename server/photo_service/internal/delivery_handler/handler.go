@@ -1,47 +1,60 @@
 package deliveryhandler
 
 import (
-	"bytes"
+	"context"
 	"crypto/rand"
-	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
 	"math/big"
-	"photo_service/internal/repository"
+	"shared/eventbus"
+	"shared/photo"
+	"sync"
 
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
-	_ "github.com/lib/pq"
-	amqp "github.com/rabbitmq/amqp091-go"
-	"github.com/sirupsen/logrus"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/math/fixed"
 )
 
-type CreatePhotoMessage struct {
-	UserId   int
-	Username string
+// eventSource identifies photo_service in the envelopes it publishes
+const eventSource = "photo_service"
+
+// Handler handles the messages of the work queues of photo_service and
+// publishes the results through Publisher
+type Handler struct {
+	Publisher eventbus.Publisher
 }
 
-func Handle(d amqp.Delivery, repository *repository.Repository) {
-	message := CreatePhotoMessage{}
-	err := json.Unmarshal(d.Body, &message)
+// Handle generates the default avatar of a newly registered user
+func (h *Handler) Handle(ctx context.Context, envelope *eventbus.Envelope) error {
+	message := photo.CreatePhotoMessage{}
+	err := envelope.Decode(&message)
 	if err != nil {
-		logrus.Fatalln(err)
+		return eventbus.Permanent(err)
 	}
 
-	size := 200
-	avatar, err := createAvatar(size, message.Username[:2])
+	avatar, err := createAvatar(maxAvatarSize, initials(message.Username))
+	if err != nil {
+		return err
+	}
+	avatars, err := renderAvatarSizes(avatar)
 	if err != nil {
-		logrus.Fatalln(err)
+		return err
 	}
-	buffer := &bytes.Buffer{}
-	png.Encode(buffer, avatar)
-	repository.UpdateDbAvatar(buffer.Bytes(), message.UserId)
+	return h.publishAvatars(ctx, message.UserId, avatars)
+}
+
+// initials returns the first two characters of a username, usernames may be
+// shorter and contain multibyte characters
+func initials(username string) string {
+	runes := []rune(username)
+	if len(runes) == 0 {
+		return "?"
+	}
+	return string(runes[:min(2, len(runes))])
 }
 
 func createAvatar(size int, initials string) (*image.RGBA, error) {
@@ -49,19 +62,19 @@ func createAvatar(size int, initials string) (*image.RGBA, error) {
 	colors := []string{"#fe6f47", "#6b82e2", "#b0b0b0", "#f08080", "#ff80be"}
 	randomNum, err := rand.Int(rand.Reader, big.NewInt(int64(len(colors))))
 	if err != nil {
-		logrus.Fatalln(err)
+		return nil, err
 	}
 	bgColor, err := ParseHexColor(colors[randomNum.Int64()])
 	if err != nil {
-		logrus.Fatalln(err)
+		return nil, err
 	}
 	background := image.NewRGBA(image.Rect(0, 0, width, height))
 	draw.Draw(background, background.Bounds(), &image.Uniform{C: bgColor}, image.Point{}, draw.Src)
 	err = drawText(background, initials)
 	if err != nil {
-		logrus.Fatalln(err)
+		return nil, err
 	}
-	return background, err
+	return background, nil
 }
 
 func ParseHexColor(s string) (c color.RGBA, err error) {
@@ -81,15 +94,23 @@ func ParseHexColor(s string) (c color.RGBA, err error) {
 	return
 }
 
+// parseFont parses the font of the initials once, a *truetype.Font is read only
+// and is shared by all workers, every drawText call creates its own face
+var parseFont = sync.OnceValues(func() (*truetype.Font, error) {
+	return freetype.ParseFont(goregular.TTF)
+})
+
 func drawText(canvas *image.RGBA, text string) error {
 	var (
-		fgColor  image.Image
-		fontFace *truetype.Font
-		err      error
-		fontSize = 128.0
+		fgColor image.Image
+		// Scaled to the canvas, 128pt on a 200px avatar
+		fontSize = float64(canvas.Rect.Dx()) * 0.64
 	)
 	fgColor = image.White
-	fontFace, err = freetype.ParseFont(goregular.TTF)
+	fontFace, err := parseFont()
+	if err != nil {
+		return err
+	}
 	fontDrawer := &font.Drawer{
 		Dst: canvas,
 		Src: fgColor,
@@ -107,5 +128,5 @@ func drawText(canvas *image.RGBA, text string) error {
 		Y: yPosition,
 	}
 	fontDrawer.DrawString(text)
-	return err
+	return nil
 }
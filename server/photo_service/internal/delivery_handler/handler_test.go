@@ -0,0 +1,24 @@
+package deliveryhandler
+
+import "testing"
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		want     string
+	}{
+		{"takes the first two characters", "johnny_doe", "jo"},
+		{"keeps multibyte characters whole", "джон_доу", "дж"},
+		{"keeps emoji whole", "😀😃smile", "😀😃"},
+		{"short username", "j", "j"},
+		{"empty username", "", "?"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := initials(test.username); got != test.want {
+				t.Errorf("initials(%q) = %q, want %q", test.username, got, test.want)
+			}
+		})
+	}
+}
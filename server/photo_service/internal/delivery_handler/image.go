@@ -0,0 +1,103 @@
+package deliveryhandler
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	amqpparams "photo_service/internal/amqp_params"
+	"shared/eventbus"
+	"shared/photo"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	maxImagePixels = 40_000_000
+	maxImageSide   = 2048
+	thumbnailSide  = 320
+)
+
+// HandleImage processes a post image uploaded through post_service and sends the
+// result back. Invalid uploads are reported back instead of being dropped, so
+// post_service can mark them as failed.
+func (h *Handler) HandleImage(ctx context.Context, envelope *eventbus.Envelope) error {
+	message := photo.ProcessImageMessage{}
+	err := envelope.Decode(&message)
+	if err != nil {
+		return eventbus.Permanent(err)
+	}
+
+	result, err := processImage(message.Data)
+	if err != nil {
+		logrus.WithField("imageId", message.ImageId).Warnln(err)
+		result = &photo.ImageProcessedMessage{Error: err.Error()}
+	}
+	result.ImageId = message.ImageId
+
+	resultEnvelope, err := eventbus.NewEnvelope(eventSource, result)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return h.Publisher.Publish(ctx, amqpparams.ImageResultQueue, resultEnvelope)
+}
+
+// processImage validates the upload, re-encodes it as PNG which strips any
+// metadata, downscales it to maxImageSide and renders a thumbnail
+func processImage(data []byte) (*photo.ImageProcessedMessage, error) {
+	source, err := decodeUpload(data)
+	if err != nil {
+		return nil, err
+	}
+
+	full := fitInto(source, maxImageSide)
+	thumbnail := fitInto(source, thumbnailSide)
+	fullBytes, err := encodePNG(full)
+	if err != nil {
+		return nil, err
+	}
+	thumbnailBytes, err := encodePNG(thumbnail)
+	if err != nil {
+		return nil, err
+	}
+	return &photo.ImageProcessedMessage{
+		Image:     fullBytes,
+		Thumbnail: thumbnailBytes,
+		Width:     full.Bounds().Dx(),
+		Height:    full.Bounds().Dy(),
+	}, nil
+}
+
+// fitInto scales the image down, keeping its aspect ratio, so that its longest side is at most side
+func fitInto(source image.Image, side int) image.Image {
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= side && height <= side {
+		return source
+	}
+	if width >= height {
+		height = max(1, height*side/width)
+		width = side
+	} else {
+		width = max(1, width*side/height)
+		height = side
+	}
+	target := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(target, target.Bounds(), source, bounds, draw.Src, nil)
+	return target
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := png.Encode(buffer, img); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,98 @@
+// Package worker runs the handlers of photo_service on a fixed number of
+// goroutines fed by the consumers of its queues
+package worker
+
+import (
+	"context"
+	"fmt"
+	amqpparams "photo_service/internal/amqp_params"
+	"shared/eventbus"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+type job struct {
+	queue    string
+	delivery amqp.Delivery
+	handler  eventbus.Handler
+}
+
+type Pool struct {
+	AmqpParams *amqpparams.AmqpParams
+	Workers    int
+
+	jobs      chan job
+	consumers []string
+	feeders   sync.WaitGroup
+	workers   sync.WaitGroup
+}
+
+var _ eventbus.Subscriber = &Pool{}
+
+func New(amqpParams *amqpparams.AmqpParams, workers int) *Pool {
+	return &Pool{
+		AmqpParams: amqpParams,
+		Workers:    workers,
+		jobs:       make(chan job),
+	}
+}
+
+// Subscribe starts consuming queue with manual acks, its deliveries are handled
+// by the workers. The consumer is resumed whenever the connection comes back.
+func (p *Pool) Subscribe(queue string, handler eventbus.Handler) error {
+	consumer := "photo_service." + queue
+	deliveries := p.AmqpParams.Consume(queue, consumer)
+	p.consumers = append(p.consumers, consumer)
+	p.feeders.Add(1)
+	go func() {
+		defer p.feeders.Done()
+		for d := range deliveries {
+			p.jobs <- job{queue: queue, delivery: d, handler: handler}
+		}
+	}()
+	return nil
+}
+
+// Start runs the workers, Subscribe should be called before so no delivery waits for a feeder
+func (p *Pool) Start() {
+	for i := 0; i < p.Workers; i++ {
+		p.workers.Add(1)
+		go func() {
+			defer p.workers.Done()
+			for j := range p.jobs {
+				p.AmqpParams.Dispatch(j.queue, j.delivery, j.handler)
+			}
+		}()
+	}
+	// The jobs channel is closed once every consumer is cancelled and drained
+	go func() {
+		p.feeders.Wait()
+		close(p.jobs)
+	}()
+}
+
+// Shutdown stops consuming and waits until the deliveries the broker already
+// sent are handled and acked. If ctx expires first, the unacked ones are
+// redelivered once the channel is closed.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	const op = "worker.Shutdown"
+
+	for _, consumer := range p.consumers {
+		if err := p.AmqpParams.Cancel(consumer); err != nil {
+			logrus.WithField("op", op).Errorln(err)
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", op, ctx.Err())
+	}
+}
@@ -7,11 +7,16 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"post_service/internal/amqp"
 	"post_service/internal/handler"
+	"post_service/internal/jwks"
 	"post_service/internal/metrics"
+	"post_service/internal/model"
 	"post_service/internal/repository"
 	"post_service/internal/service"
 	"post_service/internal/storage"
+	"shared/migrate"
+	"shared/outbox"
 	"syscall"
 	"time"
 
@@ -30,9 +35,41 @@ func main() {
 		logrus.Fatalln("couldn't load env variables", err)
 	}
 
+	// `main migrate ...` manages the database schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(storage.Open(), os.Args[2:]); err != nil {
+			logrus.Fatalln(err)
+		}
+		return
+	}
+
+	// `main upgrade-queues` redeclares the queues left by older releases and exits
+	if len(os.Args) > 1 && os.Args[1] == "upgrade-queues" {
+		upgraded, err := amqp.UpgradeQueues()
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		logrus.Infof("upgraded queues: %v", upgraded)
+		return
+	}
+
+	// `main import-legacy` moves the posts out of the database shared with user_service and exits
+	if len(os.Args) > 1 && os.Args[1] == "import-legacy" {
+		imported, err := runImportLegacy()
+		if err != nil {
+			logrus.Fatalln(err)
+		}
+		logrus.Infof("imported %d posts, %d comments, %d reactions, %d images and %d authors",
+			imported.Posts, imported.Comments, imported.Reactions, imported.Images, imported.Authors)
+		return
+	}
+
 	// Init Storage
 	storage := storage.New()
 
+	// Connect to RabbitMQ instance
+	amqpHandler := amqp.New()
+
 	// Init Grpc Client
 	grpcClient, err := grpc_client.New(
 		context.Background(),
@@ -44,10 +81,65 @@ func main() {
 		logrus.WithError(err).Fatalln("failed to initialize grpc client")
 	}
 
+	// Init token verification keys, tokens are verified over gRPC when JWKS_URL is not set
+	var keyCache *jwks.Cache
+	if jwksUrl := os.Getenv("JWKS_URL"); jwksUrl != "" {
+		keyCache = jwks.New(jwksUrl, 10*time.Minute)
+	}
+
 	// Init Repository, Service and Handler
 	postRepository := &repository.PostRepository{Db: storage.Db}
-	postService := &service.PostService{PostRepository: postRepository}
-	handler := &handler.Handler{GrpcClient: grpcClient, PostService: postService}
+	reactionRepository := &repository.ReactionRepository{Db: storage.Db}
+	imageRepository := &repository.ImageRepository{Db: storage.Db}
+	tagRepository := &repository.TagRepository{Db: storage.Db}
+	authorService := &service.AuthorService{
+		AuthorRepository: &repository.AuthorRepository{Db: storage.Db},
+		GrpcClient:       grpcClient,
+	}
+	mentionService := &service.MentionService{
+		MentionRepository: &repository.MentionRepository{Db: storage.Db},
+		AuthorService:     authorService,
+		GrpcClient:        grpcClient,
+	}
+	postService := &service.PostService{
+		PostRepository:     postRepository,
+		ReactionRepository: reactionRepository,
+		ImageRepository:    imageRepository,
+		TagRepository:      tagRepository,
+		GrpcClient:         grpcClient,
+		AuthorService:      authorService,
+		MentionService:     mentionService,
+		AvatarBaseUrl:      os.Getenv("AVATAR_BASE_URL"),
+	}
+	imageService := &service.ImageService{
+		PostRepository:  postRepository,
+		ImageRepository: imageRepository,
+		Publisher:       amqpHandler,
+	}
+	reactionService := &service.ReactionService{PostRepository: postRepository, ReactionRepository: reactionRepository}
+	commentRepository := &repository.CommentRepository{Db: storage.Db}
+	commentService := &service.CommentService{
+		PostRepository:    postRepository,
+		CommentRepository: commentRepository,
+		AuthorService:     authorService,
+		MentionService:    mentionService,
+	}
+	notificationService := &service.NotificationService{
+		NotificationRepository: &repository.NotificationRepository{Db: storage.Db},
+		AuthorService:          authorService,
+		AvatarBaseUrl:          os.Getenv("AVATAR_BASE_URL"),
+	}
+	handler := &handler.Handler{
+		GrpcClient:          grpcClient,
+		Keys:                keyCache,
+		GrpcFallback:        os.Getenv("AUTH_GRPC_FALLBACK") == "true",
+		PostService:         postService,
+		CommentService:      commentService,
+		ReactionService:     reactionService,
+		ImageService:        imageService,
+		NotificationService: notificationService,
+		TagService:          &service.TagService{TagRepository: tagRepository},
+	}
 
 	// Run Server
 	server := &http.Server{
@@ -59,6 +151,44 @@ func main() {
 	}
 	go server.ListenAndServe()
 
+	// Background jobs run until shutdown, relay post events from the outbox to RabbitMQ
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	outboxRelay := outbox.Relay{
+		Repository: &outbox.Repository{Db: storage.Db},
+		Publisher:  amqpHandler,
+		Interval:   outbox.DefaultInterval,
+		BatchSize:  outbox.DefaultBatchSize,
+	}
+	go func() {
+		outboxRelay.Run(backgroundCtx)
+		close(relayDone)
+	}()
+
+	// Deleted posts can be restored for a while, then they are removed for good
+	postPurger := service.PostPurger{
+		PostRepository: postRepository,
+		Interval:       service.DefaultPurgeInterval,
+		BatchSize:      service.DefaultPurgeBatchSize,
+	}
+	go postPurger.Run(backgroundCtx)
+
+	// Consume processed images sent back by photo_service
+	if err := amqpHandler.Subscribe(amqp.ImageResultQueue, imageService.HandleProcessedImage); err != nil {
+		logrus.Fatalln(err)
+	}
+
+	// Keep the local copy of the authors up to date
+	if err := amqpHandler.Subscribe(amqp.UserEventsQueue, authorService.HandleUserEvent); err != nil {
+		logrus.Fatalln(err)
+	}
+
+	// Notify users about what happens to them
+	if err := amqpHandler.Subscribe(amqp.NotificationsQueue, notificationService.HandleEvent); err != nil {
+		logrus.Fatalln(err)
+	}
+	go notificationService.Run(backgroundCtx)
+
 	// Run metrics server
 	go func() {
 		_ = metrics.Listen(":9081")
@@ -75,7 +205,46 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logrus.Errorf("HTTP server shutdown error")
 	}
+	stopBackground()
+	<-relayDone
 	storage.Stop()
+	amqpHandler.Close()
 
 	logrus.Info("application stopped")
 }
+
+// runImportLegacy implements the import-legacy subcommand, see service.LegacyImporter
+func runImportLegacy() (model.LegacyImport, error) {
+	s := storage.New()
+	defer s.Stop()
+	legacy := storage.OpenLegacy()
+	defer legacy.Stop()
+
+	grpcClient, err := grpc_client.New(
+		context.Background(),
+		fmt.Sprintf("host.docker.internal:%s", os.Getenv("GRPC_PORT")),
+		10*time.Second,
+		3,
+	)
+	if err != nil {
+		return model.LegacyImport{}, err
+	}
+	importer := &service.LegacyImporter{
+		LegacyRepository: &repository.LegacyRepository{Source: legacy.Db, Db: s.Db},
+		AuthorService: &service.AuthorService{
+			AuthorRepository: &repository.AuthorRepository{Db: s.Db},
+			GrpcClient:       grpcClient,
+		},
+	}
+	return importer.Run()
+}
+
+// runMigrate implements the migrate subcommand, see migrate.Run
+func runMigrate(s *storage.Storage, args []string) error {
+	defer s.Stop()
+	migrator, err := s.Migrator()
+	if err != nil {
+		return err
+	}
+	return migrate.Run(context.Background(), migrator, args)
+}
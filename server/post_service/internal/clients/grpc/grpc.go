@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	authv1 "post_service/pkg/grpc/auth"
 	"time"
@@ -14,6 +15,8 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+var ErrUserNotFound = errors.New("user not found")
+
 type GrpcClient struct {
 	api authv1.AuthClient
 }
@@ -65,6 +68,106 @@ func (g *GrpcClient) Authenticate(ctx context.Context, token string) (*authv1.Au
 	return resp, nil
 }
 
+func (g *GrpcClient) GetFollowing(ctx context.Context, userId int) ([]int, error) {
+	const op = "grpc.GetFollowing"
+
+	resp, err := g.api.GetFollowing(ctx, &authv1.GetFollowingRequest{
+		UserId: int64(userId),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s: unexpected status code %d", op, resp.StatusCode)
+	}
+
+	userIds := make([]int, 0, len(resp.UserIds))
+	for _, id := range resp.UserIds {
+		userIds = append(userIds, int(id))
+	}
+	return userIds, nil
+}
+
+func (g *GrpcClient) GetUser(ctx context.Context, userId int) (*authv1.User, error) {
+	const op = "grpc.GetUser"
+
+	resp, err := g.api.GetUser(ctx, &authv1.GetUserRequest{
+		UserId: int64(userId),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.StatusCode == 404 {
+		return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s: unexpected status code %d", op, resp.StatusCode)
+	}
+
+	return resp.User, nil
+}
+
+// BatchGetUsers returns the users with the given ids keyed by id, users that
+// don't exist are missing from the map
+func (g *GrpcClient) BatchGetUsers(ctx context.Context, userIds []int) (map[int]*authv1.User, error) {
+	const op = "grpc.BatchGetUsers"
+
+	ids := make([]int64, 0, len(userIds))
+	for _, id := range userIds {
+		ids = append(ids, int64(id))
+	}
+	resp, err := g.api.BatchGetUsers(ctx, &authv1.BatchGetUsersRequest{
+		UserIds: ids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("%s: unexpected status code %d", op, resp.StatusCode)
+	}
+
+	users := make(map[int]*authv1.User, len(resp.Users))
+	for _, user := range resp.Users {
+		users[int(user.Id)] = user
+	}
+	return users, nil
+}
+
+func (g *GrpcClient) ResolveUsername(ctx context.Context, username string) (int, error) {
+	const op = "grpc.ResolveUsername"
+
+	resp, err := g.api.ResolveUsername(ctx, &authv1.ResolveUsernameRequest{
+		Username: username,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.StatusCode == 404 {
+		return 0, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("%s: unexpected status code %d", op, resp.StatusCode)
+	}
+
+	return int(resp.UserId), nil
+}
+
+func (g *GrpcClient) CheckUserExists(ctx context.Context, userId int) (bool, error) {
+	const op = "grpc.CheckUserExists"
+
+	resp, err := g.api.CheckUserExists(ctx, &authv1.CheckUserExistsRequest{
+		UserId: int64(userId),
+	})
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if resp.StatusCode != 200 {
+		return false, fmt.Errorf("%s: unexpected status code %d", op, resp.StatusCode)
+	}
+
+	return resp.Exists, nil
+}
+
 func InterceptorLogger(l *logrus.Logger) grpclog.Logger {
 	return grpclog.LoggerFunc(func(ctx context.Context, lvl grpclog.Level, msg string, fields ...any) {
 		l.Log(l.GetLevel())
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"io"
 	"net/http"
+	"post_service/internal/jwks"
 	"post_service/internal/metrics"
 	"post_service/internal/middleware"
+	"post_service/internal/model"
 	"post_service/internal/model/request"
 	"post_service/internal/model/response"
 	"post_service/internal/service"
@@ -19,29 +22,78 @@ import (
 type HandlerIn interface {
 	New() http.Handler
 	GetPost(c *gin.Context)
+	GetFeed(c *gin.Context)
+	GetTimeline(c *gin.Context)
+	SearchPosts(c *gin.Context)
+	GetPostsByTag(c *gin.Context)
+	GetTrendingTags(c *gin.Context)
 	NewPost(c *gin.Context)
 	UpdatePost(c *gin.Context)
 	DeletePost(c *gin.Context)
+	RestorePost(c *gin.Context)
+	GetPostRevisions(c *gin.Context)
+	GetComments(c *gin.Context)
+	NewComment(c *gin.Context)
+	UpdateComment(c *gin.Context)
+	DeleteComment(c *gin.Context)
+	React(c *gin.Context)
+	Unreact(c *gin.Context)
+	AttachImage(c *gin.Context)
+	GetImage(c *gin.Context)
+	GetNotifications(c *gin.Context)
+	GetUnreadCount(c *gin.Context)
+	MarkNotificationsRead(c *gin.Context)
 }
 
 type Handler struct {
-	GrpcClient  *grpc_client.GrpcClient
-	PostService *service.PostService
+	GrpcClient          *grpc_client.GrpcClient
+	Keys                *jwks.Cache
+	GrpcFallback        bool
+	PostService         *service.PostService
+	CommentService      *service.CommentService
+	ReactionService     *service.ReactionService
+	ImageService        *service.ImageService
+	NotificationService *service.NotificationService
+	TagService          *service.TagService
 }
 
 var _ HandlerIn = &Handler{}
 
 func (h *Handler) New() http.Handler {
 	router := gin.Default()
-	authMiddleware := middleware.AuthMiddleware{GrpcClient: h.GrpcClient}
+	authMiddleware := middleware.AuthMiddleware{
+		GrpcClient:   h.GrpcClient,
+		Keys:         h.Keys,
+		GrpcFallback: h.GrpcFallback,
+	}
+
+	// Images are referenced from <img> tags, which can't send the Authorization header
+	router.GET("/postApi/getImage", h.GetImage)
 
 	postApi := router.Group("/postApi")
 	postApi.Use(authMiddleware.Run)
 	{
 		postApi.GET("/getPost", h.GetPost)
+		postApi.GET("/getFeed", h.GetFeed)
+		postApi.GET("/getTimeline", h.GetTimeline)
+		postApi.GET("/searchPosts", h.SearchPosts)
+		postApi.GET("/getPostsByTag", h.GetPostsByTag)
+		postApi.GET("/getTrendingTags", h.GetTrendingTags)
 		postApi.POST("/newPost", h.NewPost)
 		postApi.PUT("/updatePost", h.UpdatePost)
 		postApi.DELETE("/deletePost", h.DeletePost)
+		postApi.PUT("/restorePost", h.RestorePost)
+		postApi.GET("/getPostRevisions", h.GetPostRevisions)
+		postApi.GET("/getComments", h.GetComments)
+		postApi.POST("/newComment", h.NewComment)
+		postApi.PUT("/updateComment", h.UpdateComment)
+		postApi.DELETE("/deleteComment", h.DeleteComment)
+		postApi.POST("/react", h.React)
+		postApi.DELETE("/unreact", h.Unreact)
+		postApi.POST("/attachImage", h.AttachImage)
+		postApi.GET("/getNotifications", h.GetNotifications)
+		postApi.GET("/getUnreadCount", h.GetUnreadCount)
+		postApi.PUT("/markNotificationsRead", h.MarkNotificationsRead)
 	}
 
 	return router.Handler()
@@ -61,7 +113,11 @@ func (h *Handler) GetPost(c *gin.Context) {
 		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
 		return
 	}
-	post, err := h.PostService.GetPost(int(postId))
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	post, err := h.PostService.GetPost(int(postId), userId)
 	if err != nil {
 		logrus.WithField("op", op).Errorf(err.Error())
 		c.JSON(404, response.BasicResponse{Status: 404, Message: "No post with that id was found"})
@@ -70,6 +126,119 @@ func (h *Handler) GetPost(c *gin.Context) {
 	c.JSON(200, response.PostResponse{Status: 200, Message: "OK", Post: *post})
 }
 
+func (h *Handler) GetFeed(c *gin.Context) {
+	const op = "handler.GetFeed"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	authorId, err := h.getQueryInt(op, "userId", c)
+	if err != nil {
+		return
+	}
+	cursor, err := h.getQueryCursor(op, c)
+	if err != nil {
+		return
+	}
+	limit, err := h.getQueryInt(op, "limit", c)
+	if err != nil {
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	options, err := h.getListOptions(op, c)
+	if err != nil {
+		return
+	}
+	posts, nextCursor, err := h.PostService.GetFeed(authorId, userId, cursor, limit, options)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.FeedResponse{Status: 200, Message: "OK", Posts: posts, NextCursor: nextCursor})
+}
+
+func (h *Handler) GetTimeline(c *gin.Context) {
+	const op = "handler.GetTimeline"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	cursor, err := h.getQueryCursor(op, c)
+	if err != nil {
+		return
+	}
+	limit, err := h.getQueryInt(op, "limit", c)
+	if err != nil {
+		return
+	}
+	options, err := h.getListOptions(op, c)
+	if err != nil {
+		return
+	}
+	posts, nextCursor, err := h.PostService.GetTimeline(userId, cursor, limit, options)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.FeedResponse{Status: 200, Message: "OK", Posts: posts, NextCursor: nextCursor})
+}
+
+// SearchPosts finds posts by their message. Results can be narrowed down to an
+// author (authorId) and a creation time range (from, to as RFC 3339). They are
+// ranked, so they are paged by offset rather than by cursor.
+func (h *Handler) SearchPosts(c *gin.Context) {
+	const op = "handler.SearchPosts"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	var filter model.PostSearchFilter
+	var err error
+	if filter.AuthorId, err = h.getQueryInt(op, "authorId", c); err != nil {
+		return
+	}
+	if filter.From, err = h.getQueryTime(op, "from", c); err != nil {
+		return
+	}
+	if filter.To, err = h.getQueryTime(op, "to", c); err != nil {
+		return
+	}
+	offset, err := h.getQueryInt(op, "offset", c)
+	if err != nil {
+		return
+	}
+	limit, err := h.getQueryInt(op, "limit", c)
+	if err != nil {
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	results, nextOffset, err := h.PostService.SearchPosts(c.Query("q"), filter, userId, offset, limit)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.SearchPostsResponse{Status: 200, Message: "OK", Results: results, NextOffset: nextOffset})
+}
+
 func (h *Handler) NewPost(c *gin.Context) {
 	const op = "handler.NewPost"
 
@@ -157,6 +326,337 @@ func (h *Handler) DeletePost(c *gin.Context) {
 	c.JSON(200, response.BasicResponse{Status: 200, Message: "OK"})
 }
 
+// RestorePost brings back a post its owner deleted, as long as it hasn't been purged yet
+func (h *Handler) RestorePost(c *gin.Context) {
+	const op = "handler.RestorePost"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	var request request.PostIdRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	err = h.PostService.RestorePost(request.PostId, userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.BasicResponse{Status: 200, Message: "OK"})
+}
+
+// GetPostRevisions lists the earlier messages of a post (postId), the most recent first.
+// Those of a deleted post are only listed to its owner.
+func (h *Handler) GetPostRevisions(c *gin.Context) {
+	const op = "handler.GetPostRevisions"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	postId, err := strconv.ParseInt(c.Query("postId"), 10, 0)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	revisions, err := h.PostService.GetPostRevisions(int(postId), userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(404, response.BasicResponse{Status: 404, Message: "No post with that id was found"})
+		return
+	}
+	c.JSON(200, response.PostRevisionsResponse{Status: 200, Message: "OK", Revisions: revisions})
+}
+
+func (h *Handler) GetComments(c *gin.Context) {
+	const op = "handler.GetComments"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	postId, err := strconv.ParseInt(c.Query("postId"), 10, 0)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	parentId, err := h.getQueryInt(op, "parentId", c)
+	if err != nil {
+		return
+	}
+	cursor, err := h.getQueryInt(op, "cursor", c)
+	if err != nil {
+		return
+	}
+	limit, err := h.getQueryInt(op, "limit", c)
+	if err != nil {
+		return
+	}
+	comments, nextCursor, err := h.CommentService.GetComments(int(postId), parentId, cursor, limit)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.CommentListResponse{Status: 200, Message: "OK", Comments: comments, NextCursor: nextCursor})
+}
+
+func (h *Handler) NewComment(c *gin.Context) {
+	const op = "handler.NewComment"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	var request request.NewCommentRequest
+	err = c.BindJSON(&request)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+
+	commentId, err := h.CommentService.NewComment(request.PostId, request.ParentId, request.Message, userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.CommentIdResponse{Status: 200, Message: "New comment created!", CommentId: commentId})
+}
+
+func (h *Handler) UpdateComment(c *gin.Context) {
+	const op = "handler.UpdateComment"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	var request request.UpdateCommentRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+
+	err = h.CommentService.UpdateComment(request.CommentId, request.Message, userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.BasicResponse{Status: 200, Message: "Updated comment successfully!"})
+}
+
+func (h *Handler) DeleteComment(c *gin.Context) {
+	const op = "handler.DeleteComment"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	var request request.CommentIdRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	err = h.CommentService.DeleteComment(request.CommentId, userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.BasicResponse{Status: 200, Message: "OK"})
+}
+
+func (h *Handler) React(c *gin.Context) {
+	const op = "handler.React"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	var request request.ReactionRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	err = h.ReactionService.AddReaction(request.PostId, request.Reaction, userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.BasicResponse{Status: 200, Message: "OK"})
+}
+
+func (h *Handler) Unreact(c *gin.Context) {
+	const op = "handler.Unreact"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	var request request.PostIdRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	err = h.ReactionService.RemoveReaction(request.PostId, userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.BasicResponse{Status: 200, Message: "OK"})
+}
+
+func (h *Handler) AttachImage(c *gin.Context) {
+	const op = "handler.AttachImage"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	postId, err := strconv.ParseInt(c.PostForm("postId"), 10, 0)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	file, _, err := c.Request.FormFile("image")
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, service.MaxImageSize+1))
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+
+	imageId, err := h.ImageService.AttachImage(int(postId), data, userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(202, response.ImageIdResponse{Status: 202, Message: "Image is being processed", ImageId: imageId})
+}
+
+func (h *Handler) GetImage(c *gin.Context) {
+	const op = "handler.GetImage"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	imageId, err := strconv.ParseInt(c.Query("imageId"), 10, 0)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	data, err := h.ImageService.GetImage(int(imageId), c.Query("size") == "thumbnail")
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(404, response.BasicResponse{Status: 404, Message: "No image with that id was found"})
+		return
+	}
+	// Processed images never change, so they can be cached forever
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(200, "image/png", data)
+}
+
+// getListOptions parses the sort, from and to query parameters of the post list endpoints.
+// sort is newest or oldest, from and to bound the creation time of the posts in RFC 3339.
+func (h *Handler) getListOptions(op string, c *gin.Context) (model.PostListOptions, error) {
+	from, err := h.getQueryTime(op, "from", c)
+	if err != nil {
+		return model.PostListOptions{}, err
+	}
+	to, err := h.getQueryTime(op, "to", c)
+	if err != nil {
+		return model.PostListOptions{}, err
+	}
+	return model.PostListOptions{Sort: c.Query("sort"), From: from, To: to}, nil
+}
+
+// getQueryTime parses an optional RFC 3339 query parameter, an absent parameter yields the zero time
+func (h *Handler) getQueryTime(op string, target string, c *gin.Context) (time.Time, error) {
+	value := c.Query(target)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	result, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return time.Time{}, err
+	}
+
+	return result, nil
+}
+
 func (h *Handler) getUserId(op string, target string, c *gin.Context) (int, error) {
 	userId, err := strconv.ParseInt(c.Param(target), 10, 0)
 	if err != nil {
@@ -167,3 +667,31 @@ func (h *Handler) getUserId(op string, target string, c *gin.Context) (int, erro
 
 	return int(userId), nil
 }
+
+// getQueryCursor parses the optional cursor query parameter of a list of posts
+func (h *Handler) getQueryCursor(op string, c *gin.Context) (model.PostCursor, error) {
+	var cursor model.PostCursor
+	if err := cursor.UnmarshalText([]byte(c.Query("cursor"))); err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return model.PostCursor{}, err
+	}
+
+	return cursor, nil
+}
+
+// getQueryInt parses an optional integer query parameter, an absent parameter yields 0
+func (h *Handler) getQueryInt(op string, target string, c *gin.Context) (int, error) {
+	value := c.Query(target)
+	if value == "" {
+		return 0, nil
+	}
+	result, err := strconv.ParseInt(value, 10, 0)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return 0, err
+	}
+
+	return int(result), nil
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"post_service/internal/metrics"
+	"post_service/internal/model/request"
+	"post_service/internal/model/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetNotifications pages through the notifications of the user, the most recently active first.
+// Notifications are reordered as they aggregate, one that moves up while paging shows up on the first page again.
+func (h *Handler) GetNotifications(c *gin.Context) {
+	const op = "handler.GetNotifications"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	cursor, err := h.getQueryInt(op, "cursor", c)
+	if err != nil {
+		return
+	}
+	limit, err := h.getQueryInt(op, "limit", c)
+	if err != nil {
+		return
+	}
+	notifications, nextCursor, err := h.NotificationService.GetNotifications(userId, cursor, limit)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.NotificationListResponse{
+		Status:        200,
+		Message:       "OK",
+		Notifications: notifications,
+		NextCursor:    nextCursor,
+	})
+}
+
+func (h *Handler) GetUnreadCount(c *gin.Context) {
+	const op = "handler.GetUnreadCount"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	count, err := h.NotificationService.CountUnread(userId)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.UnreadCountResponse{Status: 200, Message: "OK", UnreadCount: count})
+}
+
+func (h *Handler) MarkNotificationsRead(c *gin.Context) {
+	const op = "handler.MarkNotificationsRead"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	var request request.MarkNotificationsReadRequest
+	err = c.BindJSON(&request)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: "Bad Request"})
+		return
+	}
+	err = h.NotificationService.MarkRead(userId, request.NotificationIds)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.BasicResponse{Status: 200, Message: "OK"})
+}
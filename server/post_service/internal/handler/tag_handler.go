@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"post_service/internal/metrics"
+	"post_service/internal/model/response"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func (h *Handler) GetPostsByTag(c *gin.Context) {
+	const op = "handler.GetPostsByTag"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	cursor, err := h.getQueryCursor(op, c)
+	if err != nil {
+		return
+	}
+	limit, err := h.getQueryInt(op, "limit", c)
+	if err != nil {
+		return
+	}
+	userId, err := h.getUserId(op, "userId", c)
+	if err != nil {
+		return
+	}
+	options, err := h.getListOptions(op, c)
+	if err != nil {
+		return
+	}
+	posts, nextCursor, err := h.PostService.GetPostsByTag(c.Query("tag"), userId, cursor, limit, options)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.FeedResponse{Status: 200, Message: "OK", Posts: posts, NextCursor: nextCursor})
+}
+
+// GetTrendingTags returns the tags trending over the window given by the window
+// query parameter, "hour" or "day"
+func (h *Handler) GetTrendingTags(c *gin.Context) {
+	const op = "handler.GetTrendingTags"
+
+	start := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(start), c.Writer.Status())
+	}()
+
+	limit, err := h.getQueryInt(op, "limit", c)
+	if err != nil {
+		return
+	}
+	tags, err := h.TagService.GetTrendingTags(c.Query("window"), limit)
+	if err != nil {
+		logrus.WithField("op", op).Errorf(err.Error())
+		c.JSON(403, response.BasicResponse{Status: 403, Message: err.Error()})
+		return
+	}
+	c.JSON(200, response.TrendingTagsResponse{Status: 200, Message: "OK", Tags: tags})
+}
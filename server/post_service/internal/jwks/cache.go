@@ -0,0 +1,154 @@
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrKeyNotFound = errors.New("jwks: signing key not found")
+	ErrUnavailable = errors.New("jwks: key set unavailable")
+)
+
+// minRefreshInterval limits how often an unknown kid can force a refetch of the key set
+const minRefreshInterval = 30 * time.Second
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+type key struct {
+	alg    string
+	public interface{}
+}
+
+// Cache keeps the public keys published at a JWKS endpoint in memory. Keys are
+// refetched after ttl, or earlier when a token signed with an unknown kid shows up
+// after a key rotation.
+type Cache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]key
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+func New(url string, ttl time.Duration) *Cache {
+	return &Cache{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   map[string]key{},
+	}
+}
+
+// Keyfunc resolves the verification key of a token by its kid header
+func (c *Cache) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("%w: token has no kid", ErrKeyNotFound)
+	}
+
+	c.mu.RLock()
+	k, ok := c.keys[kid]
+	fresh := time.Since(c.fetchedAt) < c.ttl
+	c.mu.RUnlock()
+	if !ok || !fresh {
+		if err := c.refresh(); err != nil && !ok {
+			return nil, err
+		}
+		c.mu.RLock()
+		k, ok = c.keys[kid]
+		c.mu.RUnlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+	}
+	if token.Method.Alg() != k.alg {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+	return k.public, nil
+}
+
+func (c *Cache) refresh() error {
+	const op = "jwks.refresh"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.attemptedAt) < minRefreshInterval {
+		return nil
+	}
+	c.attemptedAt = time.Now()
+
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %v", op, ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %w: unexpected status %d", op, ErrUnavailable, resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("%s: %w: %v", op, ErrUnavailable, err)
+	}
+
+	keys := make(map[string]key, len(set.Keys))
+	for _, jwk := range set.Keys {
+		public, err := parseKey(jwk)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key{alg: jwk.Alg, public: public}
+	}
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+func parseKey(jwk jwk) (interface{}, error) {
+	switch {
+	case jwk.Kty == "RSA" && jwk.Alg == jwt.SigningMethodRS256.Alg():
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && jwk.Alg == jwt.SigningMethodEdDSA.Alg():
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key %s/%s", jwk.Kty, jwk.Alg)
+	}
+}
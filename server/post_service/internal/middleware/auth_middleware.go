@@ -2,18 +2,33 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	grpc_client "post_service/internal/clients/grpc"
+	"post_service/internal/jwks"
 	"post_service/internal/model/response"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
 )
 
+// AuthMiddleware verifies access tokens locally against the cached user_service
+// keys when Keys is set, and through the Auth gRPC service otherwise. With
+// GrpcFallback enabled, tokens whose signing key can't be resolved locally are
+// passed on to gRPC as well.
 type AuthMiddleware struct {
-	GrpcClient *grpc_client.GrpcClient
+	GrpcClient   *grpc_client.GrpcClient
+	Keys         *jwks.Cache
+	GrpcFallback bool
+}
+
+type accessClaims struct {
+	UserId   int    `json:"userId"`
+	Username string `json:"username"`
+	jwt.RegisteredClaims
 }
 
 func (a *AuthMiddleware) Run(c *gin.Context) {
@@ -30,6 +45,24 @@ func (a *AuthMiddleware) Run(c *gin.Context) {
 		return
 	}
 	token := headerArr[1]
+
+	if a.Keys != nil {
+		claims, err := a.verifyLocally(token)
+		if err == nil {
+			c.AddParam("userId", fmt.Sprintf("%d", claims.UserId))
+			c.AddParam("username", claims.Username)
+			c.Next()
+			return
+		}
+		keyMissing := errors.Is(err, jwks.ErrKeyNotFound) || errors.Is(err, jwks.ErrUnavailable)
+		if !a.GrpcFallback || !keyMissing {
+			logrus.WithField("op", "middleware.AuthMiddleware").Warnln(err)
+			c.JSON(401, response.BasicResponse{Status: 401, Message: "Unauthorized"})
+			c.Abort()
+			return
+		}
+	}
+
 	ctx, ctxFunc := context.WithTimeout(context.Background(), 5*time.Second)
 	defer ctxFunc()
 	authResponse, err := a.GrpcClient.Authenticate(ctx, token)
@@ -47,3 +80,16 @@ func (a *AuthMiddleware) Run(c *gin.Context) {
 	c.AddParam("username", authResponse.Username)
 	c.Next()
 }
+
+// verifyLocally checks the signature and expiry of the token without calling user_service.
+// Session revocation is not visible here, a revoked token stays usable until it expires.
+func (a *AuthMiddleware) verifyLocally(token string) (*accessClaims, error) {
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, a.Keys.Keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
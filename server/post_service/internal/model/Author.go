@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// Author is the copy of a user's public data post_service keeps, it is updated from user events
+type Author struct {
+	UserId        int
+	Username      string
+	DisplayName   string
+	AvatarVersion int
+	UpdatedAt     time.Time
+}
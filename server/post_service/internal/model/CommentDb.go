@@ -0,0 +1,13 @@
+package model
+
+type CommentDb struct {
+	CommentId  int       `json:"comment_id"`
+	PostId     int       `json:"post_id"`
+	ParentId   *int      `json:"parent_id"`
+	Message    string    `json:"message"`
+	UserId     int       `json:"user_id"`
+	Username   string    `json:"username"`
+	Deleted    bool      `json:"deleted"`
+	ReplyCount int       `json:"reply_count"`
+	Mentions   []Mention `json:"mentions"`
+}
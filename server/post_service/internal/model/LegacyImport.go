@@ -0,0 +1,12 @@
+package model
+
+// LegacyImport counts what was moved out of the database post_service used to
+// share with user_service
+type LegacyImport struct {
+	Posts     int64
+	Comments  int64
+	Reactions int64
+	Images    int64
+	// Authors counts the authors fetched from user_service
+	Authors int
+}
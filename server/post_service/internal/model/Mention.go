@@ -0,0 +1,11 @@
+package model
+
+// Mention is an @username in a post or a comment that links to a user. Offset and
+// Length locate it in the message in characters (Unicode code points). Username is
+// the current username of the user, it differs from the text after a rename.
+type Mention struct {
+	UserId   int    `json:"user_id"`
+	Username string `json:"username"`
+	Offset   int    `json:"offset"`
+	Length   int    `json:"length"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+const (
+	NotificationFollow   = "follow"
+	NotificationReaction = "reaction"
+	NotificationComment  = "comment"
+	NotificationReply    = "reply"
+	NotificationMention  = "mention"
+)
+
+// Notification tells a user that others did something involving them. Unread
+// notifications of the same kind about the same post aggregate every actor,
+// e.g. "5 people liked your post". PostId is 0 for follows.
+type Notification struct {
+	NotificationId int64               `json:"notification_id"`
+	Kind           string              `json:"kind"`
+	PostId         int                 `json:"post_id"`
+	ActorIds       []int               `json:"-"`
+	Actors         []NotificationActor `json:"actors"`
+	ActorCount     int                 `json:"actor_count"`
+	Read           bool                `json:"read"`
+	Seq            int                 `json:"-"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// NotificationActor is one of the most recent users behind a notification
+type NotificationActor struct {
+	UserId      int    `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarUrl   string `json:"avatar_url"`
+}
+
+// NotificationActivity is something ActorId did that UserId is notified about
+type NotificationActivity struct {
+	UserId     int
+	Kind       string
+	PostId     int
+	ActorId    int
+	OccurredAt time.Time
+}
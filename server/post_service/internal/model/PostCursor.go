@@ -0,0 +1,49 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PostCursor is where the next page of a list of posts starts: right after the
+// post PostId created at CreatedAt, in the order the list is sorted by. The zero
+// PostCursor starts from the beginning. It travels as "<unix microseconds>_<post id>",
+// Postgres keeps creation times to the microsecond so the cursor matches them exactly.
+type PostCursor struct {
+	CreatedAt time.Time
+	PostId    int
+}
+
+func (c PostCursor) IsZero() bool {
+	return c.PostId == 0
+}
+
+func (c PostCursor) MarshalText() ([]byte, error) {
+	if c.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(fmt.Sprintf("%d_%d", c.CreatedAt.UnixMicro(), c.PostId)), nil
+}
+
+func (c *PostCursor) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = PostCursor{}
+		return nil
+	}
+	createdAt, postId, ok := strings.Cut(string(text), "_")
+	if !ok {
+		return fmt.Errorf("invalid cursor")
+	}
+	micros, err := strconv.ParseInt(createdAt, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.ParseInt(postId, 10, 0)
+	if err != nil || id <= 0 {
+		return fmt.Errorf("invalid cursor")
+	}
+	*c = PostCursor{CreatedAt: time.UnixMicro(micros).UTC(), PostId: int(id)}
+	return nil
+}
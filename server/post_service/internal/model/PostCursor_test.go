@@ -0,0 +1,27 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPostCursorText(t *testing.T) {
+	cursor := PostCursor{CreatedAt: time.Date(2026, 1, 1, 12, 0, 0, 123456000, time.UTC), PostId: 42}
+	text, err := cursor.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got PostCursor
+	if err = got.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText(%q) error = %v", text, err)
+	}
+	if !got.CreatedAt.Equal(cursor.CreatedAt) || got.PostId != cursor.PostId {
+		t.Errorf("UnmarshalText(%q) = %+v, want %+v", text, got, cursor)
+	}
+
+	for _, invalid := range []string{"42", "abc_42", "1767268800000000_x", "1767268800000000_0"} {
+		if err = got.UnmarshalText([]byte(invalid)); err == nil {
+			t.Errorf("UnmarshalText(%q) error = nil, want an error", invalid)
+		}
+	}
+}
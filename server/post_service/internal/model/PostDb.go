@@ -1,9 +1,26 @@
 package model
 
+import "time"
+
+// PostDb is a post as the api returns it. Legacy posts were written before
+// post_service kept track of creation times, their CreatedAt is when it started to
+// and so is their UpdatedAt until they are edited.
 type PostDb struct {
-	PostId   int    `json:"post_id"`
-	Message  string `json:"message"`
-	UserId   int    `json:"user_id"`
-	Username string `json:"username"`
-	Avatar   []byte `json:"avatar"`
+	PostId        int            `json:"post_id"`
+	Message       string         `json:"message"`
+	UserId        int            `json:"user_id"`
+	Edited        bool           `json:"edited"`
+	Legacy        bool           `json:"legacy"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	Username      string         `json:"username"`
+	DisplayName   string         `json:"display_name"`
+	AvatarUrl     string         `json:"avatar_url"`
+	AvatarVersion int            `json:"avatar_version"`
+	CommentCount  int            `json:"comment_count"`
+	Reactions     map[string]int `json:"reactions"`
+	MyReaction    string         `json:"my_reaction"`
+	Images        []PostImage    `json:"images"`
+	Tags          []string       `json:"tags"`
+	Mentions      []Mention      `json:"mentions"`
 }
@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+const (
+	PostCreated  = "post.created"
+	PostUpdated  = "post.updated"
+	PostDeleted  = "post.deleted"
+	PostRestored = "post.restored"
+)
+
+// PostEvent is published to the post_events exchange whenever a post changes,
+// routed by its type. Message is empty for deleted and restored posts.
+type PostEvent struct {
+	Type       string
+	PostId     int
+	UserId     int
+	Message    string
+	OccurredAt time.Time
+}
+
+func (e PostEvent) EventType() string { return e.Type }
+func (PostEvent) EventVersion() int   { return 1 }
+
+const PostReacted = "post.reacted"
+
+// ReactionEvent is published to the post_events exchange when a user reacts to
+// a post for the first time, changing the reaction doesn't publish it again
+type ReactionEvent struct {
+	Type         string
+	PostId       int
+	PostAuthorId int
+	UserId       int
+	Reaction     string
+	OccurredAt   time.Time
+}
+
+func (e ReactionEvent) EventType() string { return e.Type }
+func (ReactionEvent) EventVersion() int   { return 1 }
+
+const CommentCreated = "comment.created"
+
+// CommentEvent is published to the post_events exchange when a comment is
+// written. ParentAuthorId is 0 for top level comments.
+type CommentEvent struct {
+	Type           string
+	CommentId      int
+	PostId         int
+	PostAuthorId   int
+	ParentId       int
+	ParentAuthorId int
+	UserId         int
+	Message        string
+	OccurredAt     time.Time
+}
+
+func (e CommentEvent) EventType() string { return e.Type }
+func (CommentEvent) EventVersion() int   { return 1 }
+
+const MentionCreated = "mention.created"
+
+// MentionEvent is published to the post_events exchange when users are mentioned
+// in a post or a comment, CommentId is 0 for posts. Users that were already
+// mentioned before an edit aren't part of it again.
+type MentionEvent struct {
+	Type             string
+	PostId           int
+	CommentId        int
+	UserId           int
+	MentionedUserIds []int
+	OccurredAt       time.Time
+}
+
+func (e MentionEvent) EventType() string { return e.Type }
+func (MentionEvent) EventVersion() int   { return 1 }
@@ -0,0 +1,17 @@
+package model
+
+const (
+	ImageStatusPending = "pending"
+	ImageStatusReady   = "ready"
+	ImageStatusFailed  = "failed"
+)
+
+type PostImage struct {
+	ImageId      int    `json:"image_id"`
+	PostId       int    `json:"-"`
+	Status       string `json:"status"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Url          string `json:"url"`
+	ThumbnailUrl string `json:"thumbnail_url"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+// PostListOptions orders and filters a list of posts. Sort is SortNewest or
+// SortOldest, an empty Sort lists the newest first. From and To bound the
+// creation time of the posts, To is exclusive and zero times don't filter. Legacy
+// posts, whose creation time is unknown, are left out as soon as From or To is set.
+type PostListOptions struct {
+	Sort string
+	From time.Time
+	To   time.Time
+}
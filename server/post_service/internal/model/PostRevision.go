@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// PostRevision is a message a post had before it was edited, CreatedAt is when it
+// was written. Legacy revisions are the first message of a legacy post, their
+// CreatedAt is only when post_service started to keep track of creation times.
+type PostRevision struct {
+	RevisionId int       `json:"revision_id"`
+	PostId     int       `json:"post_id"`
+	Message    string    `json:"message"`
+	Legacy     bool      `json:"legacy"`
+	CreatedAt  time.Time `json:"created_at"`
+}
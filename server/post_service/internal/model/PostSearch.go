@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// PostSearchFilter narrows a post search down, zero fields don't filter
+type PostSearchFilter struct {
+	AuthorId int
+	// From and To bound the creation time of the posts, To is exclusive. Legacy
+	// posts are left out as soon as either is set.
+	From time.Time
+	To   time.Time
+}
+
+type PostSearchResult struct {
+	Post PostDb `json:"post"`
+	// Highlight is the part of the message that matches the query, matching words are wrapped in <mark> tags
+	Highlight string  `json:"highlight"`
+	Rank      float64 `json:"rank"`
+}
@@ -0,0 +1,9 @@
+package model
+
+type TrendingTag struct {
+	Tag string `json:"tag"`
+	// Uses is the number of posts tagged within the window
+	Uses int `json:"uses"`
+	// Score is the decayed number of uses, recent uses weigh more
+	Score float64 `json:"score"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+)
+
+// UserEvent is published by user_service with a snapshot of the user's public data
+type UserEvent struct {
+	Type          string
+	UserId        int
+	Username      string
+	DisplayName   string
+	AvatarVersion int
+	OccurredAt    time.Time
+}
+
+func (e UserEvent) EventType() string { return e.Type }
+func (UserEvent) EventVersion() int   { return 1 }
+
+const UserFollowed = "user.followed"
+
+// FollowEvent is published by user_service when a user starts following another one
+type FollowEvent struct {
+	Type       string
+	FollowerId int
+	FolloweeId int
+	OccurredAt time.Time
+}
+
+func (e FollowEvent) EventType() string { return e.Type }
+func (FollowEvent) EventVersion() int   { return 1 }
@@ -0,0 +1,5 @@
+package request
+
+type CommentIdRequest struct {
+	CommentId int `json:"comment_id"`
+}
@@ -0,0 +1,6 @@
+package request
+
+// MarkNotificationsReadRequest marks the listed notifications as read, all of them when the list is empty
+type MarkNotificationsReadRequest struct {
+	NotificationIds []int64 `json:"notification_ids"`
+}
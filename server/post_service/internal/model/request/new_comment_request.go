@@ -0,0 +1,7 @@
+package request
+
+type NewCommentRequest struct {
+	PostId   int    `json:"post_id"`
+	ParentId int    `json:"parent_id"`
+	Message  string `json:"message"`
+}
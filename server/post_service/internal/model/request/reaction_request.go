@@ -0,0 +1,6 @@
+package request
+
+type ReactionRequest struct {
+	PostId   int    `json:"post_id"`
+	Reaction string `json:"reaction"`
+}
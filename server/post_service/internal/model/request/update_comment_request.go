@@ -0,0 +1,6 @@
+package request
+
+type UpdateCommentRequest struct {
+	CommentId int    `json:"comment_id"`
+	Message   string `json:"message"`
+}
@@ -0,0 +1,7 @@
+package response
+
+type CommentIdResponse struct {
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	CommentId int    `json:"comment_id"`
+}
@@ -0,0 +1,10 @@
+package response
+
+import "post_service/internal/model"
+
+type CommentListResponse struct {
+	Status     int               `json:"status"`
+	Message    string            `json:"message"`
+	Comments   []model.CommentDb `json:"comments"`
+	NextCursor int               `json:"next_cursor"`
+}
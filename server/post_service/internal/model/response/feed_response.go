@@ -0,0 +1,10 @@
+package response
+
+import "post_service/internal/model"
+
+type FeedResponse struct {
+	Status     int              `json:"status"`
+	Message    string           `json:"message"`
+	Posts      []model.PostDb   `json:"posts"`
+	NextCursor model.PostCursor `json:"next_cursor"`
+}
@@ -0,0 +1,7 @@
+package response
+
+type ImageIdResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	ImageId int    `json:"image_id"`
+}
@@ -0,0 +1,10 @@
+package response
+
+import "post_service/internal/model"
+
+type NotificationListResponse struct {
+	Status        int                  `json:"status"`
+	Message       string               `json:"message"`
+	Notifications []model.Notification `json:"notifications"`
+	NextCursor    int                  `json:"next_cursor"`
+}
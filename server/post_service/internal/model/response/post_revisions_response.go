@@ -0,0 +1,9 @@
+package response
+
+import "post_service/internal/model"
+
+type PostRevisionsResponse struct {
+	Status    int                  `json:"status"`
+	Message   string               `json:"message"`
+	Revisions []model.PostRevision `json:"revisions"`
+}
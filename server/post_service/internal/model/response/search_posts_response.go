@@ -0,0 +1,10 @@
+package response
+
+import "post_service/internal/model"
+
+type SearchPostsResponse struct {
+	Status     int                      `json:"status"`
+	Message    string                   `json:"message"`
+	Results    []model.PostSearchResult `json:"results"`
+	NextOffset int                      `json:"next_offset"`
+}
@@ -0,0 +1,9 @@
+package response
+
+import "post_service/internal/model"
+
+type TrendingTagsResponse struct {
+	Status  int                 `json:"status"`
+	Message string              `json:"message"`
+	Tags    []model.TrendingTag `json:"tags"`
+}
@@ -0,0 +1,7 @@
+package response
+
+type UnreadCountResponse struct {
+	Status      int    `json:"status"`
+	Message     string `json:"message"`
+	UnreadCount int    `json:"unread_count"`
+}
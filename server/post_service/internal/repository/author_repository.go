@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"fmt"
+	"post_service/internal/model"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type AuthorRepositoryIn interface {
+	GetAuthors(userIds []int) (map[int]model.Author, error)
+	UpsertAuthor(author model.Author) error
+}
+
+type AuthorRepository struct {
+	Db *sqlx.DB
+}
+
+var _ AuthorRepositoryIn = &AuthorRepository{}
+
+// GetAuthors returns the known authors keyed by user id
+func (ar *AuthorRepository) GetAuthors(userIds []int) (map[int]model.Author, error) {
+	const op = "repository.GetAuthors"
+
+	rows, err := ar.Db.Query(`SELECT user_id, username, display_name, avatar_version, updated_at
+	 FROM user_author WHERE user_id = ANY($1)`, pq.Array(userIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	authors := make(map[int]model.Author, len(userIds))
+	for rows.Next() {
+		var author model.Author
+		err = rows.Scan(&author.UserId, &author.Username, &author.DisplayName, &author.AvatarVersion, &author.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		authors[author.UserId] = author
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return authors, nil
+}
+
+// UpsertAuthor saves an author unless a newer snapshot of it is already stored,
+// so events delivered out of order never roll the data back
+func (ar *AuthorRepository) UpsertAuthor(author model.Author) error {
+	const op = "repository.UpsertAuthor"
+
+	_, err := ar.Db.Exec(`INSERT INTO user_author (user_id, username, display_name, avatar_version, updated_at)
+	 VALUES ($1, $2, $3, $4, $5)
+	 ON CONFLICT (user_id) DO UPDATE SET username = EXCLUDED.username, display_name = EXCLUDED.display_name,
+	 avatar_version = EXCLUDED.avatar_version, updated_at = EXCLUDED.updated_at
+	 WHERE user_author.updated_at <= EXCLUDED.updated_at`,
+		author.UserId, author.Username, author.DisplayName, author.AvatarVersion, author.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"post_service/internal/model"
+	"shared/outbox"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CommentRepositoryIn interface {
+	GetCommentById(commentId int) (*model.CommentDb, error)
+	GetComments(postId int, parentId int, cursor int, limit int) ([]model.CommentDb, error)
+	NewComment(
+		postId int,
+		parentId int,
+		userId int,
+		message string,
+		mentions []model.Mention,
+		messages func(commentId int) ([]outbox.Message, error),
+	) (int, error)
+	UpdateComment(commentId int, newMessage string, mentions []model.Mention, messages []outbox.Message) error
+	DeleteComment(commentId int) error
+}
+
+type CommentRepository struct {
+	Db *sqlx.DB
+}
+
+var _ CommentRepositoryIn = &CommentRepository{}
+
+// commentColumns is the select list shared by all comment queries, it expects
+// post_comment aliased as pc. Author details are filled in by the service layer.
+// Comments of deleted posts are hidden, queries join visiblePost for that.
+const commentColumns = `pc.id, pc.post_id, pc.parent_id, pc.message, pc.user_id, pc.deleted,
+	 (SELECT count(*) FROM post_comment AS r WHERE r.parent_id = pc.id AND NOT r.deleted)`
+
+const visiblePost = `JOIN user_post AS up ON up.id = pc.post_id AND up.deleted_at IS NULL`
+
+func (cr *CommentRepository) GetCommentById(commentId int) (*model.CommentDb, error) {
+	const op = "repository.GetCommentById"
+
+	comment, err := scanComment(cr.Db.QueryRow(`SELECT `+commentColumns+` FROM post_comment AS pc `+visiblePost+`
+	 WHERE pc.id = $1`, commentId))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return comment, nil
+}
+
+// GetComments returns the comments of a post from oldest to newest, paged by id.
+// A zero parentId lists top level comments, otherwise the replies to that comment.
+func (cr *CommentRepository) GetComments(postId int, parentId int, cursor int, limit int) ([]model.CommentDb, error) {
+	const op = "repository.GetComments"
+
+	rows, err := cr.Db.Query(`SELECT `+commentColumns+` FROM post_comment AS pc `+visiblePost+`
+	 WHERE pc.post_id = $1 AND pc.parent_id IS NOT DISTINCT FROM NULLIF($2, 0) AND pc.id > $3
+	 ORDER BY pc.id LIMIT $4`,
+		postId, parentId, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	comments := []model.CommentDb{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		comments = append(comments, *comment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return comments, nil
+}
+
+// NewComment inserts a comment with its mentions and, in the same transaction, the
+// outbox messages that announce it. messages is called with the id of the new comment.
+func (cr *CommentRepository) NewComment(
+	postId int,
+	parentId int,
+	userId int,
+	message string,
+	mentions []model.Mention,
+	messages func(commentId int) ([]outbox.Message, error),
+) (int, error) {
+	const op = "repository.NewComment"
+
+	tx, err := cr.Db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var commentId int
+	err = tx.QueryRow(`INSERT INTO post_comment (post_id, parent_id, user_id, message)
+	 VALUES ($1, NULLIF($2, 0), $3, $4) RETURNING id`,
+		postId, parentId, userId, message).Scan(&commentId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = setCommentMentions(tx, commentId, mentions); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	outboxMessages, err := messages(commentId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = outbox.Add(tx, outboxMessages); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return commentId, nil
+}
+
+// UpdateComment changes the message and the mentions of a comment together with
+// saving the outbox messages that announce it
+func (cr *CommentRepository) UpdateComment(
+	commentId int,
+	newMessage string,
+	mentions []model.Mention,
+	messages []outbox.Message,
+) error {
+	const op = "repository.UpdateComment"
+
+	tx, err := cr.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("UPDATE post_comment SET message = $1 WHERE id = $2", newMessage, commentId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = setCommentMentions(tx, commentId, mentions); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = outbox.Add(tx, messages); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// DeleteComment tombstones the comment instead of removing the row,
+// so the replies below it keep their place in the thread. Its mentions go with the message.
+func (cr *CommentRepository) DeleteComment(commentId int) error {
+	const op = "repository.DeleteComment"
+
+	_, err := cr.Db.Exec(`WITH deleted_mentions AS (DELETE FROM comment_mention WHERE comment_id = $1)
+	 UPDATE post_comment SET deleted = true, message = '' WHERE id = $1`, commentId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func scanComment(row rowScanner) (*model.CommentDb, error) {
+	var comment model.CommentDb
+	var parentId sql.NullInt64
+	err := row.Scan(&comment.CommentId, &comment.PostId, &parentId, &comment.Message,
+		&comment.UserId, &comment.Deleted, &comment.ReplyCount)
+	if err != nil {
+		return nil, err
+	}
+	if parentId.Valid {
+		id := int(parentId.Int64)
+		comment.ParentId = &id
+	}
+	return &comment, nil
+}
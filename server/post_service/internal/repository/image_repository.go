@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"post_service/internal/model"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrTooManyImages = errors.New("post already has the maximum number of images")
+
+type ImageRepositoryIn interface {
+	NewImage(postId int, maxImages int) (int, error)
+	SaveProcessedImage(imageId int, image, thumbnail []byte, width, height int) error
+	MarkImageFailed(imageId int) error
+	DeleteImage(imageId int) error
+	GetImageData(imageId int, thumbnail bool) ([]byte, error)
+	GetPostImages(postIds []int) (map[int][]model.PostImage, error)
+}
+
+type ImageRepository struct {
+	Db *sqlx.DB
+}
+
+var _ ImageRepositoryIn = &ImageRepository{}
+
+// NewImage registers a pending image of a post. The post row is locked while the
+// images are counted, so concurrent uploads can't exceed maxImages. Deleted posts
+// can't get new images, sql.ErrNoRows is returned for them.
+func (ir *ImageRepository) NewImage(postId int, maxImages int) (int, error) {
+	const op = "repository.NewImage"
+
+	tx, err := ir.Db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	err = tx.QueryRow("SELECT id FROM user_post WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", postId).Scan(&postId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	err = tx.QueryRow("SELECT count(*) FROM post_image WHERE post_id = $1 AND status <> $2",
+		postId, model.ImageStatusFailed).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if count >= maxImages {
+		return 0, ErrTooManyImages
+	}
+	var imageId int
+	err = tx.QueryRow("INSERT INTO post_image (post_id, status) VALUES ($1, $2) RETURNING id",
+		postId, model.ImageStatusPending).Scan(&imageId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return imageId, nil
+}
+
+func (ir *ImageRepository) SaveProcessedImage(imageId int, image, thumbnail []byte, width, height int) error {
+	const op = "repository.SaveProcessedImage"
+
+	_, err := ir.Db.Exec(`UPDATE post_image SET status = $1, image = $2, thumbnail = $3, width = $4, height = $5
+	 WHERE id = $6`, model.ImageStatusReady, image, thumbnail, width, height, imageId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (ir *ImageRepository) MarkImageFailed(imageId int) error {
+	const op = "repository.MarkImageFailed"
+
+	_, err := ir.Db.Exec("UPDATE post_image SET status = $1 WHERE id = $2", model.ImageStatusFailed, imageId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (ir *ImageRepository) DeleteImage(imageId int) error {
+	const op = "repository.DeleteImage"
+
+	_, err := ir.Db.Exec("DELETE FROM post_image WHERE id = $1", imageId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// GetImageData returns the processed PNG of an image or its thumbnail. Images of deleted posts aren't served.
+func (ir *ImageRepository) GetImageData(imageId int, thumbnail bool) ([]byte, error) {
+	const op = "repository.GetImageData"
+
+	column := "pi.image"
+	if thumbnail {
+		column = "pi.thumbnail"
+	}
+	var data []byte
+	err := ir.Db.QueryRow(`SELECT `+column+` FROM post_image AS pi JOIN user_post AS up ON up.id = pi.post_id
+	 WHERE pi.id = $1 AND pi.status = $2 AND up.deleted_at IS NULL`,
+		imageId, model.ImageStatusReady).Scan(&data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return data, nil
+}
+
+// GetPostImages returns the metadata of the images attached to the given posts keyed by post id
+func (ir *ImageRepository) GetPostImages(postIds []int) (map[int][]model.PostImage, error) {
+	const op = "repository.GetPostImages"
+
+	rows, err := ir.Db.Query(`SELECT id, post_id, status, width, height FROM post_image
+	 WHERE post_id = ANY($1) AND status <> $2 ORDER BY id`, pq.Array(postIds), model.ImageStatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	images := map[int][]model.PostImage{}
+	for rows.Next() {
+		var image model.PostImage
+		if err = rows.Scan(&image.ImageId, &image.PostId, &image.Status, &image.Width, &image.Height); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		images[image.PostId] = append(images[image.PostId], image)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return images, nil
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"post_service/internal/model"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var ErrPostsExist = errors.New("database already has posts")
+
+type LegacyRepositoryIn interface {
+	ImportPosts(tags func(message string) []string) (model.LegacyImport, error)
+	GetUserIdsWithoutAuthor(afterId, limit int) ([]int, error)
+}
+
+// LegacyRepository moves the posts post_service wrote while it shared its database
+// with user_service, Source, into its own database, Db
+type LegacyRepository struct {
+	Source *sqlx.DB
+	Db     *sqlx.DB
+}
+
+var _ LegacyRepositoryIn = &LegacyRepository{}
+
+// ImportPosts copies the posts, comments, reactions and images of Source into Db in
+// one transaction, keeping their ids. Db must not have posts yet, ErrPostsExist is
+// returned otherwise. The posts are flagged legacy and tagged with what tags finds
+// in their message, the tags are dated -infinity like the ones migration 13 backfills.
+// Tables missing from Source are skipped.
+func (l *LegacyRepository) ImportPosts(tags func(message string) []string) (model.LegacyImport, error) {
+	const op = "repository.ImportPosts"
+
+	tx, err := l.Db.Begin()
+	if err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM user_post)").Scan(&exists); err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if exists {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, ErrPostsExist)
+	}
+
+	var imported model.LegacyImport
+	if imported.Posts, err = l.importPosts(tx, tags); err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	imported.Comments, err = l.copyTable(tx, "post_comment", "id, post_id, parent_id, user_id, message, deleted")
+	if err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if imported.Reactions, err = l.copyTable(tx, "post_reaction", "post_id, user_id, reaction"); err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err = l.copyTable(tx, "post_reaction_count", "post_id, reaction, count"); err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	imported.Images, err = l.copyTable(tx, "post_image", "id, post_id, status, image, thumbnail, width, height")
+	if err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// The ids were copied, the sequences have to continue after them
+	for _, table := range []string{"user_post", "post_comment", "post_image"} {
+		_, err = tx.Exec(`SELECT setval(pg_get_serial_sequence($1, 'id'),
+		 coalesce((SELECT max(id) FROM `+table+`), 0) + 1, false)`, table)
+		if err != nil {
+			return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return imported, nil
+}
+
+func (l *LegacyRepository) importPosts(tx *sql.Tx, tags func(message string) []string) (int64, error) {
+	ok, err := l.sourceHas("user_post")
+	if err != nil || !ok {
+		return 0, err
+	}
+	rows, err := l.Source.Query("SELECT id, message, user_id FROM user_post ORDER BY id")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	for rows.Next() {
+		var postId, userId int
+		var message string
+		if err = rows.Scan(&postId, &message, &userId); err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO user_post (id, message, user_id, legacy, search_vector)
+		 VALUES ($1, $2, $3, true, to_tsvector('english', $2))`, postId, message, userId)
+		if err != nil {
+			return 0, err
+		}
+		_, err = tx.Exec(`INSERT INTO post_tag (post_id, tag, created_at)
+		 SELECT $1, unnest($2::varchar[]), '-infinity'`, postId, pq.Array(tags(message)))
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// copyTable copies the columns of every row of table from Source into Db, ordered
+// by the first column so comments come after the comments they answer
+func (l *LegacyRepository) copyTable(tx *sql.Tx, table string, columns string) (int64, error) {
+	ok, err := l.sourceHas(table)
+	if err != nil || !ok {
+		return 0, err
+	}
+	rows, err := l.Source.Query("SELECT " + columns + " FROM " + table + " ORDER BY 1")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// Values are scanned as the driver decodes them and written back as they are
+	n := len(strings.Split(columns, ","))
+	values := make([]any, n)
+	targets := make([]any, n)
+	params := make([]string, n)
+	for i := range values {
+		targets[i] = &values[i]
+		params[i] = fmt.Sprintf("$%d", i+1)
+	}
+	insert := "INSERT INTO " + table + " (" + columns + ") VALUES (" + strings.Join(params, ", ") + ")"
+
+	var count int64
+	for rows.Next() {
+		if err = rows.Scan(targets...); err != nil {
+			return 0, err
+		}
+		if _, err = tx.Exec(insert, values...); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+func (l *LegacyRepository) sourceHas(table string) (bool, error) {
+	var exists bool
+	err := l.Source.QueryRow("SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	return exists, err
+}
+
+// GetUserIdsWithoutAuthor pages through the users who wrote, commented on or reacted
+// to posts and have no user_author row, ordered by id and starting after afterId
+func (l *LegacyRepository) GetUserIdsWithoutAuthor(afterId, limit int) ([]int, error) {
+	const op = "repository.GetUserIdsWithoutAuthor"
+
+	userIds := []int{}
+	err := l.Db.Select(&userIds, `SELECT user_id FROM (
+	 SELECT user_id FROM user_post UNION SELECT user_id FROM post_comment UNION SELECT user_id FROM post_reaction
+	 ) AS users
+	 WHERE user_id > $1 AND NOT EXISTS (SELECT 1 FROM user_author AS ua WHERE ua.user_id = users.user_id)
+	 ORDER BY user_id LIMIT $2`, afterId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return userIds, nil
+}
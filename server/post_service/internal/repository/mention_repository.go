@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"post_service/internal/model"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type MentionRepositoryIn interface {
+	GetPostMentions(postIds []int) (map[int][]model.Mention, error)
+	GetCommentMentions(commentIds []int) (map[int][]model.Mention, error)
+}
+
+type MentionRepository struct {
+	Db *sqlx.DB
+}
+
+var _ MentionRepositoryIn = &MentionRepository{}
+
+// GetPostMentions returns the mentions of the given posts keyed by post id, in the order they appear
+func (mr *MentionRepository) GetPostMentions(postIds []int) (map[int][]model.Mention, error) {
+	const op = "repository.GetPostMentions"
+
+	mentions, err := mr.getMentions(`SELECT post_id, user_id, start_offset, length FROM post_mention
+	 WHERE post_id = ANY($1) ORDER BY post_id, start_offset`, postIds)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return mentions, nil
+}
+
+// GetCommentMentions returns the mentions of the given comments keyed by comment id, in the order they appear
+func (mr *MentionRepository) GetCommentMentions(commentIds []int) (map[int][]model.Mention, error) {
+	const op = "repository.GetCommentMentions"
+
+	mentions, err := mr.getMentions(`SELECT comment_id, user_id, start_offset, length FROM comment_mention
+	 WHERE comment_id = ANY($1) ORDER BY comment_id, start_offset`, commentIds)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return mentions, nil
+}
+
+func (mr *MentionRepository) getMentions(query string, ids []int) (map[int][]model.Mention, error) {
+	rows, err := mr.Db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := map[int][]model.Mention{}
+	for rows.Next() {
+		var id int
+		var mention model.Mention
+		if err = rows.Scan(&id, &mention.UserId, &mention.Offset, &mention.Length); err != nil {
+			return nil, err
+		}
+		mentions[id] = append(mentions[id], mention)
+	}
+	return mentions, rows.Err()
+}
+
+// setPostMentions replaces the mentions of a post
+func setPostMentions(tx *sql.Tx, postId int, mentions []model.Mention) error {
+	if _, err := tx.Exec("DELETE FROM post_mention WHERE post_id = $1", postId); err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		_, err := tx.Exec("INSERT INTO post_mention (post_id, user_id, start_offset, length) VALUES ($1, $2, $3, $4)",
+			postId, mention.UserId, mention.Offset, mention.Length)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCommentMentions replaces the mentions of a comment
+func setCommentMentions(tx *sql.Tx, commentId int, mentions []model.Mention) error {
+	if _, err := tx.Exec("DELETE FROM comment_mention WHERE comment_id = $1", commentId); err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		_, err := tx.Exec(`INSERT INTO comment_mention (comment_id, user_id, start_offset, length)
+		 VALUES ($1, $2, $3, $4)`, commentId, mention.UserId, mention.Offset, mention.Length)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"post_service/internal/model"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type NotificationRepositoryIn interface {
+	AddActivities(eventId string, activities []model.NotificationActivity) (bool, error)
+	GetNotifications(userId int, cursor int, limit int) ([]model.Notification, error)
+	CountUnread(userId int) (int, error)
+	MarkRead(userId int, notificationIds []int64) (int64, error)
+	DeleteProcessedEvents(olderThan time.Duration) (int64, error)
+}
+
+type NotificationRepository struct {
+	Db *sqlx.DB
+}
+
+var _ NotificationRepositoryIn = &NotificationRepository{}
+
+// aboutVisiblePost leaves out the notifications about posts that have been deleted,
+// a zero post_id means the notification isn't about a post
+const aboutVisiblePost = `(post_id = 0 OR EXISTS (SELECT 1 FROM user_post AS up
+	 WHERE up.id = notification.post_id AND up.deleted_at IS NULL))`
+
+// AddActivities records the activities an event produced. An activity joins the
+// unread notification of the same user, kind and post if there is one, an actor
+// is counted once. Events that were already processed are skipped, in which
+// case false is returned.
+func (nr *NotificationRepository) AddActivities(eventId string, activities []model.NotificationActivity) (bool, error) {
+	const op = "repository.AddActivities"
+
+	tx, err := nr.Db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO notification_event (event_id) VALUES ($1) ON CONFLICT DO NOTHING", eventId)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+	for _, activity := range activities {
+		_, err = tx.Exec(`INSERT INTO notification (user_id, kind, post_id, actor_ids, created_at, updated_at)
+		 VALUES ($1, $2, $3, ARRAY[$4::integer], $5, $5)
+		 ON CONFLICT (user_id, kind, post_id) WHERE NOT read DO UPDATE SET
+		 actor_ids = array_prepend($4::integer, array_remove(notification.actor_ids, $4::integer)),
+		 seq = nextval('notification_seq'),
+		 updated_at = GREATEST(notification.updated_at, EXCLUDED.updated_at)`,
+			activity.UserId, activity.Kind, activity.PostId, activity.ActorId, activity.OccurredAt)
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return true, nil
+}
+
+// GetNotifications returns up to limit notifications of a user, the most
+// recently active first. A non zero cursor returns the ones with a lower seq.
+// seq isn't stable: aggregating a new actor gives the notification a new seq,
+// which moves it above any cursor handed out before.
+func (nr *NotificationRepository) GetNotifications(userId int, cursor int, limit int) ([]model.Notification, error) {
+	const op = "repository.GetNotifications"
+
+	rows, err := nr.Db.Query(`SELECT id, kind, post_id, actor_ids, read, seq, created_at, updated_at
+	 FROM notification WHERE user_id = $1 AND ($2 = 0 OR seq < $2) AND `+aboutVisiblePost+`
+	 ORDER BY seq DESC LIMIT $3`,
+		userId, cursor, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	notifications := []model.Notification{}
+	for rows.Next() {
+		var notification model.Notification
+		var actorIds pq.Int64Array
+		err = rows.Scan(&notification.NotificationId, &notification.Kind, &notification.PostId, &actorIds,
+			&notification.Read, &notification.Seq, &notification.CreatedAt, &notification.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		notification.ActorIds = make([]int, len(actorIds))
+		for i, actorId := range actorIds {
+			notification.ActorIds[i] = int(actorId)
+		}
+		notification.ActorCount = len(actorIds)
+		notifications = append(notifications, notification)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return notifications, nil
+}
+
+func (nr *NotificationRepository) CountUnread(userId int) (int, error) {
+	const op = "repository.CountUnread"
+
+	var count int
+	err := nr.Db.QueryRow(`SELECT count(*) FROM notification
+	 WHERE user_id = $1 AND NOT read AND `+aboutVisiblePost, userId).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// MarkRead marks notifications of a user as read, all of them when notificationIds is empty
+func (nr *NotificationRepository) MarkRead(userId int, notificationIds []int64) (int64, error) {
+	const op = "repository.MarkRead"
+
+	var result sql.Result
+	var err error
+	if len(notificationIds) == 0 {
+		result, err = nr.Db.Exec("UPDATE notification SET read = true WHERE user_id = $1 AND NOT read", userId)
+	} else {
+		result, err = nr.Db.Exec("UPDATE notification SET read = true WHERE user_id = $1 AND NOT read AND id = ANY($2)",
+			userId, pq.Array(notificationIds))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteProcessedEvents forgets the ids of events processed more than olderThan ago
+func (nr *NotificationRepository) DeleteProcessedEvents(olderThan time.Duration) (int64, error) {
+	const op = "repository.DeleteProcessedEvents"
+
+	result, err := nr.Db.Exec("DELETE FROM notification_event WHERE received_at < now() - $1 * interval '1 millisecond'",
+		olderThan.Milliseconds())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return result.RowsAffected()
+}
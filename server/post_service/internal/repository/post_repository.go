@@ -1,79 +1,444 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"post_service/internal/model"
+	"shared/outbox"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type PostRepositoryIn interface {
 	GetPostById(postId int) (*model.PostDb, error)
-	NewPost(message string, userId int) error
-	UpdatePost(postId int, newMessage string) error
-	DeletePost(postId int) error
+	GetPosts(authorId int, cursor model.PostCursor, limit int, options model.PostListOptions) ([]model.PostDb, error)
+	GetPostsByAuthors(
+		authorIds []int,
+		cursor model.PostCursor,
+		limit int,
+		options model.PostListOptions,
+	) ([]model.PostDb, error)
+	GetPostsByTag(tag string, cursor model.PostCursor, limit int, options model.PostListOptions) ([]model.PostDb, error)
+	NewPost(
+		message string,
+		userId int,
+		tags []string,
+		mentions []model.Mention,
+		messages func(postId int) ([]outbox.Message, error),
+	) (int, error)
+	UpdatePost(postId int, newMessage string, tags []string, mentions []model.Mention, messages []outbox.Message) error
+	DeletePost(postId int, messages []outbox.Message) error
+	RestorePost(postId int, userId int, window time.Duration, messages []outbox.Message) error
+	PurgeDeletedPosts(olderThan time.Duration, limit int) (int64, error)
+	GetPostRevisions(postId int, viewerId int) ([]model.PostRevision, error)
+	SearchPosts(query string, filter model.PostSearchFilter, offset int, limit int) ([]model.PostSearchResult, error)
 }
 
+var ErrPostNotRestorable = errors.New("post doesn't exist or can't be restored anymore")
+
 type PostRepository struct {
 	Db *sqlx.DB
 }
 
 var _ PostRepositoryIn = &PostRepository{}
 
+// postColumns is the select list shared by all post queries, it expects user_post
+// aliased as up. Author details are owned by user_service and filled in by the service layer.
+// Queries have to skip deleted posts themselves, with up.deleted_at IS NULL.
+const postColumns = `up.id, up.message, up.user_id, up.edited_at IS NOT NULL, up.legacy, up.created_at, up.updated_at,
+	 (SELECT count(*) FROM post_comment AS pc WHERE pc.post_id = up.id AND NOT pc.deleted)`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
 func (p *PostRepository) GetPostById(postId int) (*model.PostDb, error) {
 	const op = "repository.GetPostById"
 
-	var post *model.PostDb = &model.PostDb{}
-	err := p.Db.QueryRow(`SELECT up.id, message, user_id, username, avatar FROM user_post AS up
-	 INNER JOIN app_user AS au ON up.user_id = au.id WHERE up.id = $1`,
-		postId).Scan(&post.PostId, &post.Message, &post.UserId, &post.Username, &post.Avatar)
+	post, err := scanPost(p.Db.QueryRow(`SELECT `+postColumns+` FROM user_post AS up
+	 WHERE up.id = $1 AND up.deleted_at IS NULL`,
+		postId))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	return post, nil
 }
 
-func (p *PostRepository) NewPost(message string, userId int) error {
-	const op = "repository.NewPost"
+// GetPosts returns up to limit posts in the order options ask for, newest first by
+// default. Paging is keyset based: only posts past the cursor post are returned,
+// so rows inserted while a client is paging never shift the following pages.
+// A zero authorId or cursor disables the corresponding filter.
+func (p *PostRepository) GetPosts(
+	authorId int,
+	cursor model.PostCursor,
+	limit int,
+	options model.PostListOptions,
+) ([]model.PostDb, error) {
+	const op = "repository.GetPosts"
 
-	stmt, err := p.Db.Prepare("INSERT INTO user_post (message, user_id) VALUES ($1, $2)")
+	conditions, orderBy := postListClauses(2, options)
+	rows, err := p.Db.Query(`SELECT `+postColumns+` FROM user_post AS up
+	 WHERE ($1 = 0 OR up.user_id = $1) AND `+conditions+` AND up.deleted_at IS NULL
+	 `+orderBy+` LIMIT $6`,
+		authorId, nullTime(cursor.CreatedAt), cursor.PostId, nullTime(options.From), nullTime(options.To), limit)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	_, err = stmt.Exec(message, userId)
+	posts, err := scanPosts(rows)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	stmt.Close()
-	return nil
+	return posts, nil
 }
 
-func (p *PostRepository) UpdatePost(postId int, newMessage string) error {
-	const op = "repository.UpdatePost"
+// GetPostsByAuthors pages through the posts written by any of authorIds the same way GetPosts does
+func (p *PostRepository) GetPostsByAuthors(
+	authorIds []int,
+	cursor model.PostCursor,
+	limit int,
+	options model.PostListOptions,
+) ([]model.PostDb, error) {
+	const op = "repository.GetPostsByAuthors"
 
-	stmt, err := p.Db.Prepare("UPDATE user_post SET message = $1 WHERE id = $2")
+	conditions, orderBy := postListClauses(2, options)
+	rows, err := p.Db.Query(`SELECT `+postColumns+` FROM user_post AS up
+	 WHERE up.user_id = ANY($1) AND `+conditions+` AND up.deleted_at IS NULL
+	 `+orderBy+` LIMIT $6`,
+		pq.Array(authorIds), nullTime(cursor.CreatedAt), cursor.PostId, nullTime(options.From), nullTime(options.To),
+		limit)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return posts, nil
+}
+
+// GetPostsByTag pages through the posts tagged with tag the same way GetPosts does
+func (p *PostRepository) GetPostsByTag(
+	tag string,
+	cursor model.PostCursor,
+	limit int,
+	options model.PostListOptions,
+) ([]model.PostDb, error) {
+	const op = "repository.GetPostsByTag"
+
+	conditions, orderBy := postListClauses(2, options)
+	rows, err := p.Db.Query(`SELECT `+postColumns+` FROM post_tag AS pt
+	 JOIN user_post AS up ON up.id = pt.post_id
+	 WHERE pt.tag = $1 AND `+conditions+` AND up.deleted_at IS NULL
+	 `+orderBy+` LIMIT $6`,
+		tag, nullTime(cursor.CreatedAt), cursor.PostId, nullTime(options.From), nullTime(options.To), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	posts, err := scanPosts(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return posts, nil
+}
+
+// NewPost inserts a post with its tags and mentions and, in the same transaction,
+// the outbox messages that announce it. messages is called with the id of the new post.
+func (p *PostRepository) NewPost(
+	message string,
+	userId int,
+	tags []string,
+	mentions []model.Mention,
+	messages func(postId int) ([]outbox.Message, error),
+) (int, error) {
+	const op = "repository.NewPost"
+
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var postId int
+	err = tx.QueryRow(`INSERT INTO user_post (message, user_id, search_vector)
+	 VALUES ($1, $2, to_tsvector('english', $1)) RETURNING id`,
+		message, userId).Scan(&postId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = setPostTags(tx, postId, tags); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = setPostMentions(tx, postId, mentions); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	_, err = stmt.Exec(newMessage, postId)
+	outboxMessages, err := messages(postId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = outbox.Add(tx, outboxMessages); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return postId, nil
+}
+
+// UpdatePost changes the message, the tags and the mentions of a post together
+// with saving the outbox messages that announce it. A changed message is kept as a revision.
+// Deleted posts can't be updated, sql.ErrNoRows is returned for them.
+func (p *PostRepository) UpdatePost(
+	postId int,
+	newMessage string,
+	tags []string,
+	mentions []model.Mention,
+	messages []outbox.Message,
+) error {
+	const op = "repository.UpdatePost"
+
+	err := p.inTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO post_revision (post_id, message, created_at, legacy)
+		 SELECT id, message, coalesce(edited_at, created_at), legacy AND edited_at IS NULL FROM user_post
+		 WHERE id = $1 AND message <> $2 AND deleted_at IS NULL`,
+			postId, newMessage)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(`UPDATE user_post SET message = $1, search_vector = to_tsvector('english', $1),
+		 edited_at = CASE WHEN message <> $1 THEN now() ELSE edited_at END, updated_at = now()
+		 WHERE id = $2 AND deleted_at IS NULL`, newMessage, postId)
+		if err != nil {
+			return err
+		}
+		// The post may have been deleted since the service looked it up
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return sql.ErrNoRows
+		}
+		if err = setPostTags(tx, postId, tags); err != nil {
+			return err
+		}
+		if err = setPostMentions(tx, postId, mentions); err != nil {
+			return err
+		}
+		return outbox.Add(tx, messages)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	stmt.Close()
 	return nil
 }
 
-func (p *PostRepository) DeletePost(postId int) error {
+// DeletePost soft deletes a post together with saving the outbox messages that
+// announce it. Until it is restored or purged the post is left out of the post lists,
+// search and trending tags, its comments, images and notifications are hidden and
+// it can't be changed, commented on or reacted to.
+// Posts that are already deleted aren't announced again, sql.ErrNoRows is returned for them.
+func (p *PostRepository) DeletePost(postId int, messages []outbox.Message) error {
 	const op = "repository.DeletePost"
 
-	stmt, err := p.Db.Prepare("DELETE FROM user_post WHERE id = $1")
+	err := p.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec("UPDATE user_post SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL", postId)
+		if err != nil {
+			return err
+		}
+		// The post may have been deleted since the service looked it up
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return sql.ErrNoRows
+		}
+		return outbox.Add(tx, messages)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	_, err = stmt.Exec(postId)
+	return nil
+}
+
+// RestorePost brings back a post userId deleted less than window ago together with
+// saving the outbox messages that announce it, otherwise ErrPostNotRestorable is returned
+func (p *PostRepository) RestorePost(postId int, userId int, window time.Duration, messages []outbox.Message) error {
+	const op = "repository.RestorePost"
+
+	err := p.inTx(func(tx *sql.Tx) error {
+		result, err := tx.Exec(`UPDATE user_post SET deleted_at = NULL
+		 WHERE id = $1 AND user_id = $2 AND deleted_at > now() - $3 * interval '1 millisecond'`,
+			postId, userId, window.Milliseconds())
+		if err != nil {
+			return err
+		}
+		restored, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if restored == 0 {
+			return ErrPostNotRestorable
+		}
+		return outbox.Add(tx, messages)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	stmt.Close()
 	return nil
 }
+
+// PurgeDeletedPosts removes up to limit posts deleted more than olderThan ago for
+// good, along with everything that belongs to them, and returns how many it removed
+func (p *PostRepository) PurgeDeletedPosts(olderThan time.Duration, limit int) (int64, error) {
+	const op = "repository.PurgeDeletedPosts"
+
+	result, err := p.Db.Exec(`DELETE FROM user_post WHERE id IN (SELECT id FROM user_post
+	 WHERE deleted_at < now() - $1 * interval '1 millisecond' ORDER BY deleted_at LIMIT $2)`,
+		olderThan.Milliseconds(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return result.RowsAffected()
+}
+
+// GetPostRevisions returns the earlier messages of a post, the most recent first.
+// The revisions of a deleted post are only shown to its owner viewerId, for
+// everyone else sql.ErrNoRows is returned like for a post that doesn't exist.
+func (p *PostRepository) GetPostRevisions(postId int, viewerId int) ([]model.PostRevision, error) {
+	const op = "repository.GetPostRevisions"
+
+	var visible bool
+	err := p.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_post
+	 WHERE id = $1 AND (deleted_at IS NULL OR user_id = $2))`, postId, viewerId).Scan(&visible)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !visible {
+		return nil, fmt.Errorf("%s: %w", op, sql.ErrNoRows)
+	}
+	rows, err := p.Db.Query(`SELECT pr.id, pr.post_id, pr.message, pr.legacy, pr.created_at
+	 FROM post_revision AS pr JOIN user_post AS up ON up.id = pr.post_id
+	 WHERE pr.post_id = $1 AND (up.deleted_at IS NULL OR up.user_id = $2) ORDER BY pr.id DESC`,
+		postId, viewerId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	revisions := []model.PostRevision{}
+	for rows.Next() {
+		var revision model.PostRevision
+		if err = rows.Scan(&revision.RevisionId, &revision.PostId, &revision.Message, &revision.Legacy,
+			&revision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return revisions, nil
+}
+
+// SearchPosts returns the posts whose message matches query, best matches first.
+// query is parsed like a web search: quoted phrases, "or" and -excluded words are supported.
+func (p *PostRepository) SearchPosts(
+	query string,
+	filter model.PostSearchFilter,
+	offset int,
+	limit int,
+) ([]model.PostSearchResult, error) {
+	const op = "repository.SearchPosts"
+
+	rows, err := p.Db.Query(`SELECT `+postColumns+`,
+	 ts_headline('english', up.message, q, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2'),
+	 ts_rank(up.search_vector, q) AS rank
+	 FROM user_post AS up, websearch_to_tsquery('english', $1) AS q
+	 WHERE up.search_vector @@ q AND up.deleted_at IS NULL AND ($2 = 0 OR up.user_id = $2)
+	 AND ($3::timestamptz IS NULL OR (up.created_at >= $3 AND NOT up.legacy))
+	 AND ($4::timestamptz IS NULL OR (up.created_at < $4 AND NOT up.legacy))
+	 ORDER BY rank DESC, up.id DESC OFFSET $5 LIMIT $6`,
+		query, filter.AuthorId, nullTime(filter.From), nullTime(filter.To), offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	results := []model.PostSearchResult{}
+	for rows.Next() {
+		var result model.PostSearchResult
+		err = rows.Scan(&result.Post.PostId, &result.Post.Message, &result.Post.UserId, &result.Post.Edited,
+			&result.Post.Legacy, &result.Post.CreatedAt, &result.Post.UpdatedAt, &result.Post.CommentCount,
+			&result.Highlight, &result.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return results, nil
+}
+
+// postListClauses returns the conditions that page a list of posts (user_post aliased
+// as up) from its cursor and bound the creation time of the posts, together with the
+// ORDER BY they need. The creation time and the post id of the cursor, from and to
+// are the parameters $n to $n+3. Posts are ordered by creation time, the post id
+// breaks ties between posts created at the same time.
+func postListClauses(n int, options model.PostListOptions) (string, string) {
+	comparison, direction := "<", "DESC"
+	if options.Sort == model.SortOldest {
+		comparison, direction = ">", "ASC"
+	}
+	conditions := fmt.Sprintf(`($%[1]d::timestamptz IS NULL OR (up.created_at, up.id) %[5]s ($%[1]d, $%[2]d))
+	 AND ($%[3]d::timestamptz IS NULL OR (up.created_at >= $%[3]d AND NOT up.legacy))
+	 AND ($%[4]d::timestamptz IS NULL OR (up.created_at < $%[4]d AND NOT up.legacy))`,
+		n, n+1, n+2, n+3, comparison)
+	return conditions, "ORDER BY up.created_at " + direction + ", up.id " + direction
+}
+
+// nullTime turns the zero time into NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func (p *PostRepository) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := p.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanPosts(rows *sql.Rows) ([]model.PostDb, error) {
+	defer rows.Close()
+
+	posts := []model.PostDb{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, *post)
+	}
+	return posts, rows.Err()
+}
+
+func scanPost(row rowScanner) (*model.PostDb, error) {
+	var post model.PostDb
+	err := row.Scan(&post.PostId, &post.Message, &post.UserId, &post.Edited, &post.Legacy, &post.CreatedAt,
+		&post.UpdatedAt, &post.CommentCount)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
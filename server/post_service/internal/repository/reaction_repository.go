@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"shared/outbox"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ReactionRepositoryIn interface {
+	AddReaction(postId int, userId int, reaction string, messages []outbox.Message) error
+	RemoveReaction(postId int, userId int) error
+	GetReactionCounts(postIds []int) (map[int]map[string]int, error)
+	GetUserReactions(postIds []int, userId int) (map[int]string, error)
+}
+
+type ReactionRepository struct {
+	Db *sqlx.DB
+}
+
+var _ ReactionRepositoryIn = &ReactionRepository{}
+
+// AddReaction sets the reaction of a user to a post, replacing the previous one.
+// The per-post counters are updated in the same transaction while the reaction
+// row is locked, so concurrent requests can't double count. The outbox messages
+// are saved only when the user didn't react to the post before.
+func (rr *ReactionRepository) AddReaction(postId int, userId int, reaction string, messages []outbox.Message) error {
+	const op = "repository.AddReaction"
+
+	// The row may be removed concurrently between the insert and the lock, retry in that case
+	for attempt := 0; attempt < 3; attempt++ {
+		err := rr.addReaction(postId, userId, reaction, messages)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: too much contention", op)
+}
+
+func (rr *ReactionRepository) addReaction(postId int, userId int, reaction string, messages []outbox.Message) error {
+	tx, err := rr.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO post_reaction (post_id, user_id, reaction) VALUES ($1, $2, $3)
+	 ON CONFLICT (post_id, user_id) DO NOTHING`, postId, userId, reaction)
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		var previous string
+		err = tx.QueryRow(`SELECT reaction FROM post_reaction WHERE post_id = $1 AND user_id = $2 FOR UPDATE`,
+			postId, userId).Scan(&previous)
+		if err != nil {
+			return err
+		}
+		if previous == reaction {
+			return tx.Commit()
+		}
+		_, err = tx.Exec(`UPDATE post_reaction SET reaction = $1 WHERE post_id = $2 AND user_id = $3`,
+			reaction, postId, userId)
+		if err != nil {
+			return err
+		}
+		// Touch the counters in a fixed order, so two users swapping reactions can't deadlock
+		if previous < reaction {
+			err = decrementReaction(tx, postId, previous)
+			if err == nil {
+				err = incrementReaction(tx, postId, reaction)
+			}
+		} else {
+			err = incrementReaction(tx, postId, reaction)
+			if err == nil {
+				err = decrementReaction(tx, postId, previous)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	if err = incrementReaction(tx, postId, reaction); err != nil {
+		return err
+	}
+	if err = outbox.Add(tx, messages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (rr *ReactionRepository) RemoveReaction(postId int, userId int) error {
+	const op = "repository.RemoveReaction"
+
+	tx, err := rr.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRow(`DELETE FROM post_reaction WHERE post_id = $1 AND user_id = $2 RETURNING reaction`,
+		postId, userId).Scan(&previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = decrementReaction(tx, postId, previous); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// GetReactionCounts returns the non-zero reaction counters of the given posts keyed by post id
+func (rr *ReactionRepository) GetReactionCounts(postIds []int) (map[int]map[string]int, error) {
+	const op = "repository.GetReactionCounts"
+
+	rows, err := rr.Db.Query(`SELECT post_id, reaction, count FROM post_reaction_count
+	 WHERE post_id = ANY($1) AND count > 0`, pq.Array(postIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	counts := map[int]map[string]int{}
+	for rows.Next() {
+		var (
+			postId   int
+			reaction string
+			count    int
+		)
+		if err = rows.Scan(&postId, &reaction, &count); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if counts[postId] == nil {
+			counts[postId] = map[string]int{}
+		}
+		counts[postId][reaction] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return counts, nil
+}
+
+// GetUserReactions returns the reactions userId left on the given posts keyed by post id
+func (rr *ReactionRepository) GetUserReactions(postIds []int, userId int) (map[int]string, error) {
+	const op = "repository.GetUserReactions"
+
+	rows, err := rr.Db.Query(`SELECT post_id, reaction FROM post_reaction
+	 WHERE post_id = ANY($1) AND user_id = $2`, pq.Array(postIds), userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	reactions := map[int]string{}
+	for rows.Next() {
+		var (
+			postId   int
+			reaction string
+		)
+		if err = rows.Scan(&postId, &reaction); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		reactions[postId] = reaction
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return reactions, nil
+}
+
+func incrementReaction(tx *sql.Tx, postId int, reaction string) error {
+	_, err := tx.Exec(`INSERT INTO post_reaction_count (post_id, reaction, count) VALUES ($1, $2, 1)
+	 ON CONFLICT (post_id, reaction) DO UPDATE SET count = post_reaction_count.count + 1`,
+		postId, reaction)
+	return err
+}
+
+func decrementReaction(tx *sql.Tx, postId int, reaction string) error {
+	_, err := tx.Exec(`UPDATE post_reaction_count SET count = count - 1 WHERE post_id = $1 AND reaction = $2`,
+		postId, reaction)
+	return err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"post_service/internal/model"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type TagRepositoryIn interface {
+	GetPostTags(postIds []int) (map[int][]string, error)
+	GetTrendingTags(since time.Time, halfLife time.Duration, limit int) ([]model.TrendingTag, error)
+}
+
+type TagRepository struct {
+	Db *sqlx.DB
+}
+
+var _ TagRepositoryIn = &TagRepository{}
+
+// GetPostTags returns the tags of the given posts keyed by post id
+func (tr *TagRepository) GetPostTags(postIds []int) (map[int][]string, error) {
+	const op = "repository.GetPostTags"
+
+	rows, err := tr.Db.Query("SELECT post_id, tag FROM post_tag WHERE post_id = ANY($1) ORDER BY post_id, tag",
+		pq.Array(postIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	tags := map[int][]string{}
+	for rows.Next() {
+		var postId int
+		var tag string
+		if err = rows.Scan(&postId, &tag); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tags[postId] = append(tags[postId], tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tags, nil
+}
+
+// GetTrendingTags returns the tags used since the given time, highest score first.
+// Every use adds to the score of a tag by a weight that halves every halfLife,
+// so a tag that is used a lot right now outranks one that was used more an hour ago.
+func (tr *TagRepository) GetTrendingTags(since time.Time, halfLife time.Duration, limit int) ([]model.TrendingTag, error) {
+	const op = "repository.GetTrendingTags"
+
+	rows, err := tr.Db.Query(`SELECT pt.tag, count(*),
+	 sum(power(0.5, extract(epoch FROM now() - pt.created_at) / $2)) AS score
+	 FROM post_tag AS pt JOIN user_post AS up ON up.id = pt.post_id
+	 WHERE pt.created_at >= $1 AND up.deleted_at IS NULL
+	 GROUP BY pt.tag ORDER BY score DESC, pt.tag LIMIT $3`,
+		since, halfLife.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	tags := []model.TrendingTag{}
+	for rows.Next() {
+		var tag model.TrendingTag
+		if err = rows.Scan(&tag.Tag, &tag.Uses, &tag.Score); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tags, nil
+}
+
+// setPostTags replaces the tags of a post. Tags the post already had keep the
+// time they were added, so editing a post doesn't make its tags trend again.
+func setPostTags(tx *sql.Tx, postId int, tags []string) error {
+	_, err := tx.Exec("DELETE FROM post_tag WHERE post_id = $1 AND NOT (tag = ANY($2))", postId, pq.Array(tags))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO post_tag (post_id, tag) SELECT $1, unnest($2::varchar[])
+	 ON CONFLICT (post_id, tag) DO NOTHING`, postId, pq.Array(tags))
+	return err
+}
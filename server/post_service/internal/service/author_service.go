@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"shared/eventbus"
+	"time"
+
+	grpc_client "post_service/internal/clients/grpc"
+)
+
+type AuthorServiceIn interface {
+	GetAuthors(userIds []int) (map[int]model.Author, error)
+	HandleUserEvent(ctx context.Context, envelope *eventbus.Envelope) error
+}
+
+// AuthorService keeps the local copy of the authors of posts and comments.
+// user_service owns user data, the copy is filled from its events and, for
+// users that never produced one since the copy exists, from its gRPC API.
+type AuthorService struct {
+	AuthorRepository *repository.AuthorRepository
+	GrpcClient       *grpc_client.GrpcClient
+}
+
+var _ AuthorServiceIn = &AuthorService{}
+
+// GetAuthors returns the authors with the given ids keyed by id, users that
+// don't exist anymore are missing from the map
+func (as *AuthorService) GetAuthors(userIds []int) (map[int]model.Author, error) {
+	const op = "service.GetAuthors"
+
+	authors, err := as.AuthorRepository.GetAuthors(userIds)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	missing := []int{}
+	for _, userId := range userIds {
+		if _, ok := authors[userId]; !ok {
+			missing = append(missing, userId)
+		}
+	}
+	if len(missing) == 0 {
+		return authors, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	users, err := as.GrpcClient.BatchGetUsers(ctx, missing)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// Backfilled rows are stamped with the zero time, any event about the user overrides them
+	for _, user := range users {
+		author := model.Author{
+			UserId:        int(user.Id),
+			Username:      user.Username,
+			DisplayName:   user.DisplayName,
+			AvatarVersion: int(user.AvatarVersion),
+		}
+		if err = as.AuthorRepository.UpsertAuthor(author); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		authors[author.UserId] = author
+	}
+	return authors, nil
+}
+
+func (as *AuthorService) HandleUserEvent(ctx context.Context, envelope *eventbus.Envelope) error {
+	const op = "service.HandleUserEvent"
+
+	if envelope.Type != model.UserCreated && envelope.Type != model.UserUpdated {
+		return nil
+	}
+	var event model.UserEvent
+	if err := envelope.Decode(&event); err != nil {
+		return eventbus.Permanent(fmt.Errorf("%s: %w", op, err))
+	}
+	err := as.AuthorRepository.UpsertAuthor(model.Author{
+		UserId:        event.UserId,
+		Username:      event.Username,
+		DisplayName:   event.DisplayName,
+		AvatarVersion: event.AvatarVersion,
+		UpdatedAt:     event.OccurredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"fmt"
+	"post_service/internal/amqp"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"shared/outbox"
+	"slices"
+	"strings"
+	"time"
+)
+
+const MaxCommentLength = 2000
+
+type CommentServiceIn interface {
+	GetComments(postId int, parentId int, cursor int, limit int) ([]model.CommentDb, int, error)
+	NewComment(postId int, parentId int, message string, userId int) (int, error)
+	UpdateComment(commentId int, newMessage string, userId int) error
+	DeleteComment(commentId int, userId int) error
+}
+
+type CommentService struct {
+	PostRepository    *repository.PostRepository
+	CommentRepository *repository.CommentRepository
+	AuthorService     *AuthorService
+	MentionService    *MentionService
+}
+
+var _ CommentServiceIn = &CommentService{}
+
+func (cs *CommentService) GetComments(postId int, parentId int, cursor int, limit int) ([]model.CommentDb, int, error) {
+	const op = "service.GetComments"
+
+	limit, err := normalizePage(cursor, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	comments, err := cs.CommentRepository.GetComments(postId, parentId, cursor, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	comments, nextCursor := paginate(comments, limit, func(comment model.CommentDb) int {
+		return comment.CommentId
+	})
+	if err = cs.attachAuthors(comments); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = cs.attachMentions(comments); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return comments, nextCursor, nil
+}
+
+func (cs *CommentService) NewComment(postId int, parentId int, message string, userId int) (int, error) {
+	const op = "service.NewComment"
+
+	if err := validateComment(message); err != nil {
+		return 0, err
+	}
+	post, err := cs.PostRepository.GetPostById(postId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	parentAuthorId := 0
+	if parentId != 0 {
+		parent, err := cs.CommentRepository.GetCommentById(parentId)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		if parent.PostId != postId {
+			return 0, fmt.Errorf("parent comment belongs to another post")
+		}
+		if parent.Deleted {
+			return 0, fmt.Errorf("can't reply to a deleted comment")
+		}
+		parentAuthorId = parent.UserId
+	}
+	mentions, err := cs.MentionService.Resolve(message)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	commentId, err := cs.CommentRepository.NewComment(postId, parentId, userId, message, mentions,
+		func(commentId int) ([]outbox.Message, error) {
+			outboxMessage, err := newOutboxMessage(amqp.PostEventsExchange, &model.CommentEvent{
+				Type:           model.CommentCreated,
+				CommentId:      commentId,
+				PostId:         postId,
+				PostAuthorId:   post.UserId,
+				ParentId:       parentId,
+				ParentAuthorId: parentAuthorId,
+				UserId:         userId,
+				Message:        message,
+				OccurredAt:     time.Now().UTC(),
+			})
+			if err != nil {
+				return nil, err
+			}
+			mentionMessages, err := mentionEventMessages(postId, commentId, userId, mentions, nil)
+			return append([]outbox.Message{outboxMessage}, mentionMessages...), err
+		})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return commentId, nil
+}
+
+func (cs *CommentService) UpdateComment(commentId int, newMessage string, userId int) error {
+	const op = "service.UpdateComment"
+
+	if err := validateComment(newMessage); err != nil {
+		return err
+	}
+	commentDb, err := cs.CommentRepository.GetCommentById(commentId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if commentDb.UserId != userId {
+		return fmt.Errorf("you are not an owner of this comment")
+	}
+	if commentDb.Deleted {
+		return fmt.Errorf("comment has been deleted")
+	}
+	mentions, err := cs.MentionService.Resolve(newMessage)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	previousMentions, err := cs.MentionService.GetCommentMentions([]int{commentId})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	messages, err := mentionEventMessages(commentDb.PostId, commentId, userId, mentions, previousMentions[commentId])
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = cs.CommentRepository.UpdateComment(commentId, newMessage, mentions, messages)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (cs *CommentService) DeleteComment(commentId int, userId int) error {
+	const op = "service.DeleteComment"
+
+	commentDb, err := cs.CommentRepository.GetCommentById(commentId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if commentDb.UserId != userId {
+		return fmt.Errorf("you are not an owner of this comment")
+	}
+	err = cs.CommentRepository.DeleteComment(commentId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func validateComment(message string) error {
+	if strings.TrimSpace(message) == "" {
+		return fmt.Errorf("comment can't be empty")
+	}
+	if len(message) > MaxCommentLength {
+		return fmt.Errorf("comment can't be longer than %d characters", MaxCommentLength)
+	}
+	return nil
+}
+
+func (cs *CommentService) attachAuthors(comments []model.CommentDb) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	authorIds := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		if !slices.Contains(authorIds, comment.UserId) {
+			authorIds = append(authorIds, comment.UserId)
+		}
+	}
+	authors, err := cs.AuthorService.GetAuthors(authorIds)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Username = authors[comments[i].UserId].Username
+	}
+	return nil
+}
+
+func (cs *CommentService) attachMentions(comments []model.CommentDb) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	commentIds := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		commentIds = append(commentIds, comment.CommentId)
+	}
+	mentions, err := cs.MentionService.GetCommentMentions(commentIds)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].Mentions = mentions[comments[i].CommentId]
+		if comments[i].Mentions == nil {
+			comments[i].Mentions = []model.Mention{}
+		}
+	}
+	return nil
+}
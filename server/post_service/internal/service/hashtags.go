@@ -0,0 +1,59 @@
+package service
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MaxTagLength   = 64
+	MaxTagsPerPost = 30
+)
+
+// hashtagPattern matches a # that doesn't continue a word (so anchors in urls and
+// "C#" aren't tags) followed by letters, combining marks, digits and underscores.
+// Marks are part of the word, otherwise scripts like Devanagari would cut tags short.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_&/#])#([\p{L}\p{M}\p{N}_]+)`)
+
+// ParseHashtags returns the normalized hashtags of a message in the order they
+// first appear. Tags without a letter, like #1, are ignored.
+func ParseHashtags(message string) []string {
+	tags := []string{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(message, -1) {
+		tag, ok := NormalizeTag(match[1])
+		if !ok || slices.Contains(tags, tag) {
+			continue
+		}
+		tags = append(tags, tag)
+		if len(tags) == MaxTagsPerPost {
+			break
+		}
+	}
+	return tags
+}
+
+// NormalizeTag lowercases a tag, brings it to Unicode NFC and strips its leading #,
+// so the composed and decomposed spellings of a tag are the same tag. ok is false
+// when it isn't a valid tag.
+func NormalizeTag(tag string) (string, bool) {
+	tag = norm.NFC.String(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+	if tag == "" || utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", false
+	}
+	hasLetter := false
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r) && r != '_' {
+			return "", false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	if !hasLetter {
+		return "", false
+	}
+	return tag, true
+}
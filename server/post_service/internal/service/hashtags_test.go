@@ -0,0 +1,59 @@
+package service
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want string
+		ok   bool
+	}{
+		{"lowercases", "GoLang", "golang", true},
+		{"strips the hash", "#go", "go", true},
+		{"trims spaces", "  go ", "go", true},
+		{"keeps digits and underscores", "go_1_21", "go_1_21", true},
+		{"composes decomposed letters", "café", "café", true},
+		{"keeps composed letters", "CAF\u00c9", "caf\u00e9", true},
+		{"keeps combining marks", "हिंदी", "हिंदी", true},
+		{"needs a letter", "2024", "", false},
+		{"rejects empty tags", "#", "", false},
+		{"rejects punctuation", "go-lang", "", false},
+		{"rejects long tags", strings.Repeat("a", MaxTagLength+1), "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := NormalizeTag(test.tag)
+			if got != test.want || ok != test.ok {
+				t.Errorf("NormalizeTag(%q) = %q, %v, want %q, %v", test.tag, got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestParseHashtags(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{"finds tags in order", "#Go is fun #go #GoLang", []string{"go", "golang"}},
+		{"ignores words ending in a hash", "C# and F#", []string{}},
+		{"ignores url anchors", "see https://example.com/#intro", []string{}},
+		{"ignores html entities", "&#39;quoted&#39;", []string{}},
+		{"ignores tags without a letter", "#1 #2024", []string{}},
+		{"reads a whole tag with marks", "#हिंदी text", []string{"हिंदी"}},
+		{"merges both spellings of a tag", "#café #café", []string{"café"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseHashtags(test.message); !slices.Equal(got, test.want) {
+				t.Errorf("ParseHashtags(%q) = %q, want %q", test.message, got, test.want)
+			}
+		})
+	}
+}
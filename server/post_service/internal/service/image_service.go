@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"post_service/internal/amqp"
+	"post_service/internal/repository"
+	"shared/eventbus"
+	"shared/photo"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EventSource identifies post_service in the envelopes it publishes
+const EventSource = "post_service"
+
+const (
+	MaxPostImages = 4
+	MaxImageSize  = 10 << 20
+)
+
+var allowedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+type ImageServiceIn interface {
+	AttachImage(postId int, data []byte, userId int) (int, error)
+	HandleProcessedImage(ctx context.Context, envelope *eventbus.Envelope) error
+	GetImage(imageId int, thumbnail bool) ([]byte, error)
+}
+
+type ImageService struct {
+	PostRepository  repository.PostRepositoryIn
+	ImageRepository repository.ImageRepositoryIn
+	Publisher       eventbus.Publisher
+}
+
+var _ ImageServiceIn = &ImageService{}
+
+// AttachImage registers a pending image on the post and hands the upload over to
+// photo_service, which validates, re-encodes and thumbnails it asynchronously
+func (is *ImageService) AttachImage(postId int, data []byte, userId int) (int, error) {
+	const op = "service.AttachImage"
+
+	if len(data) == 0 || len(data) > MaxImageSize {
+		return 0, fmt.Errorf("image size should be between 1 byte and %d bytes", MaxImageSize)
+	}
+	if !allowedImageTypes[http.DetectContentType(data)] {
+		return 0, errors.New("invalid file type")
+	}
+	postDb, err := is.PostRepository.GetPostById(postId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if postDb.UserId != userId {
+		return 0, fmt.Errorf("you are not an owner of this post")
+	}
+	imageId, err := is.ImageRepository.NewImage(postId, MaxPostImages)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	envelope, err := eventbus.NewEnvelope(EventSource, &photo.ProcessImageMessage{ImageId: imageId, Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = is.Publisher.Publish(ctx, amqp.ImageProcessQueue, envelope)
+	if err != nil {
+		// Don't leave a pending image behind that will never be processed
+		_ = is.ImageRepository.DeleteImage(imageId)
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return imageId, nil
+}
+
+// HandleProcessedImage stores the result photo_service sent back for an image.
+// A rejected upload is settled once the image is marked failed, retrying
+// wouldn't change the verdict of photo_service.
+func (is *ImageService) HandleProcessedImage(ctx context.Context, envelope *eventbus.Envelope) error {
+	const op = "service.HandleProcessedImage"
+
+	var message photo.ImageProcessedMessage
+	if err := envelope.Decode(&message); err != nil {
+		return eventbus.Permanent(fmt.Errorf("%s: %w", op, err))
+	}
+	if message.Error != "" {
+		if err := is.ImageRepository.MarkImageFailed(message.ImageId); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		logrus.WithFields(logrus.Fields{"op": op, "imageId": message.ImageId}).
+			Warnln("image rejected by photo_service: ", message.Error)
+		return nil
+	}
+	err := is.ImageRepository.SaveProcessedImage(message.ImageId, message.Image, message.Thumbnail,
+		message.Width, message.Height)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (is *ImageService) GetImage(imageId int, thumbnail bool) ([]byte, error) {
+	const op = "service.GetImage"
+
+	data, err := is.ImageRepository.GetImageData(imageId, thumbnail)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"post_service/internal/amqp"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"shared/eventbus"
+	"shared/photo"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeImageRepository records what happens to the images and attaches none to
+// posts, the methods the tests don't use panic through the nil interface
+type fakeImageRepository struct {
+	repository.ImageRepositoryIn
+
+	mu        sync.Mutex
+	failed    []int
+	processed []int
+}
+
+func (f *fakeImageRepository) MarkImageFailed(imageId int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failed = append(f.failed, imageId)
+	return nil
+}
+
+func (f *fakeImageRepository) SaveProcessedImage(imageId int, image, thumbnail []byte, width, height int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.processed = append(f.processed, imageId)
+	return nil
+}
+
+func (f *fakeImageRepository) GetPostImages(postIds []int) (map[int][]model.PostImage, error) {
+	return map[int][]model.PostImage{}, nil
+}
+
+func TestHandleProcessedImage(t *testing.T) {
+	bus := eventbus.NewMemory()
+	defer bus.Close()
+	images := &fakeImageRepository{}
+	imageService := &ImageService{ImageRepository: images, Publisher: bus}
+	if err := bus.Subscribe(amqp.ImageResultQueue, imageService.HandleProcessedImage); err != nil {
+		t.Fatal(err)
+	}
+
+	results := []*photo.ImageProcessedMessage{
+		{ImageId: 1, Error: "not an image"},
+		{ImageId: 2, Image: []byte("image"), Thumbnail: []byte("thumbnail"), Width: 10, Height: 10},
+	}
+	for _, result := range results {
+		envelope, err := eventbus.NewEnvelope("photo_service", result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = bus.Publish(context.Background(), amqp.ImageResultQueue, envelope); err != nil {
+			t.Fatal(err)
+		}
+	}
+	malformed, err := eventbus.NewEnvelope("photo_service", &photo.ImageProcessedMessage{ImageId: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	malformed.Data = json.RawMessage(`{"ImageId": "three"}`)
+	if err = bus.Publish(context.Background(), amqp.ImageResultQueue, malformed); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = bus.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if len(images.failed) != 1 || images.failed[0] != 1 {
+		t.Errorf("marked %v failed, want the rejected image 1", images.failed)
+	}
+	if len(images.processed) != 1 || images.processed[0] != 2 {
+		t.Errorf("saved %v, want the processed image 2", images.processed)
+	}
+	// The rejected image is settled, only the malformed result fails and it
+	// fails permanently instead of coming back forever
+	failures := bus.Failures()
+	if len(failures) != 1 || failures[0].Envelope.Id != malformed.Id || !eventbus.IsPermanent(failures[0].Err) {
+		t.Fatalf("Failures() = %v, want the malformed result to fail permanently", failures)
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// legacyAuthorBatchSize is how many authors are asked from user_service at once,
+// it stays below the limit of BatchGetUsers
+const legacyAuthorBatchSize = 100
+
+// LegacyImporter moves the posts written while post_service shared its database
+// with user_service into its own database, then fills the local copy of the authors
+// of everything it moved from user_service
+type LegacyImporter struct {
+	LegacyRepository repository.LegacyRepositoryIn
+	AuthorService    AuthorServiceIn
+}
+
+// Run imports the posts and backfills their authors. Posts are only copied into a
+// database without posts, when there are some already the copy is skipped and only
+// the missing authors are filled, so Run can be repeated after a failed backfill.
+func (li *LegacyImporter) Run() (model.LegacyImport, error) {
+	const op = "service.LegacyImporter.Run"
+
+	imported, err := li.LegacyRepository.ImportPosts(ParseHashtags)
+	if errors.Is(err, repository.ErrPostsExist) {
+		logrus.Warnln("skipping the copy of the posts: ", err)
+	} else if err != nil {
+		return model.LegacyImport{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// GetAuthors fetches the authors it doesn't know from user_service and keeps
+	// them, users that don't exist anymore stay missing and are paged past
+	afterId := 0
+	for {
+		userIds, err := li.LegacyRepository.GetUserIdsWithoutAuthor(afterId, legacyAuthorBatchSize)
+		if err != nil {
+			return imported, fmt.Errorf("%s: %w", op, err)
+		}
+		if len(userIds) == 0 {
+			return imported, nil
+		}
+		authors, err := li.AuthorService.GetAuthors(userIds)
+		if err != nil {
+			return imported, fmt.Errorf("%s: %w", op, err)
+		}
+		imported.Authors += len(authors)
+		afterId = userIds[len(userIds)-1]
+	}
+}
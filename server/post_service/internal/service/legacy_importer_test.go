@@ -0,0 +1,95 @@
+package service
+
+import (
+	"fmt"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"testing"
+)
+
+// fakeLegacyRepository has posts by users 1 to 250 of whom the authors of users
+// divisible by 10 are known already
+type fakeLegacyRepository struct {
+	importErr error
+	imported  bool
+}
+
+func (f *fakeLegacyRepository) ImportPosts(tags func(message string) []string) (model.LegacyImport, error) {
+	if f.importErr != nil {
+		return model.LegacyImport{}, f.importErr
+	}
+	f.imported = true
+	return model.LegacyImport{Posts: 250}, nil
+}
+
+func (f *fakeLegacyRepository) GetUserIdsWithoutAuthor(afterId, limit int) ([]int, error) {
+	userIds := []int{}
+	for id := afterId + 1; id <= 250 && len(userIds) < limit; id++ {
+		if id%10 != 0 {
+			userIds = append(userIds, id)
+		}
+	}
+	return userIds, nil
+}
+
+// fakeUserService knows the users that aren't divisible by 7 and records how many
+// users it was asked for at once
+type fakeUserService struct {
+	AuthorServiceIn
+
+	batches []int
+}
+
+func (f *fakeUserService) GetAuthors(userIds []int) (map[int]model.Author, error) {
+	f.batches = append(f.batches, len(userIds))
+	authors := map[int]model.Author{}
+	for _, id := range userIds {
+		if id%7 != 0 {
+			authors[id] = model.Author{UserId: id}
+		}
+	}
+	return authors, nil
+}
+
+func TestLegacyImporterRun(t *testing.T) {
+	// Users 1 to 250 without the 25 known ones and the 32 of them that don't exist
+	const wantAuthors = 250 - 25 - 32
+
+	tests := []struct {
+		name      string
+		importErr error
+		wantPosts int64
+		wantErr   bool
+	}{
+		{"imports posts and backfills their authors", nil, 250, false},
+		{"backfills the authors of imported posts", fmt.Errorf("op: %w", repository.ErrPostsExist), 0, false},
+		{"fails when the import fails", fmt.Errorf("connection refused"), 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users := &fakeUserService{}
+			importer := &LegacyImporter{
+				LegacyRepository: &fakeLegacyRepository{importErr: test.importErr},
+				AuthorService:    users,
+			}
+			imported, err := importer.Run()
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("Run() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if imported.Posts != test.wantPosts || imported.Authors != wantAuthors {
+				t.Errorf("Run() = %+v, want %d posts and %d authors", imported, test.wantPosts, wantAuthors)
+			}
+			for _, batch := range users.batches {
+				if batch > legacyAuthorBatchSize {
+					t.Errorf("asked for %d authors at once, want at most %d", batch, legacyAuthorBatchSize)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	grpc_client "post_service/internal/clients/grpc"
+)
+
+const (
+	MaxMentionsPerMessage = 10
+	// minUsernameLength and maxUsernameLength mirror the username rules of user_service,
+	// words outside of them can't be usernames and aren't resolved
+	minUsernameLength = 6
+	maxUsernameLength = 26
+)
+
+// mentionPattern matches an @ that doesn't continue a word (so e-mail addresses
+// aren't mentions) followed by the characters a username is made of
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@/.])@([\p{L}\p{N}_.\-]+)`)
+
+type MentionServiceIn interface {
+	Resolve(message string) ([]model.Mention, error)
+	GetPostMentions(postIds []int) (map[int][]model.Mention, error)
+	GetCommentMentions(commentIds []int) (map[int][]model.Mention, error)
+}
+
+// MentionService links the @usernames of posts and comments to users. Mentions
+// are stored by user id, their usernames are filled in when they are read.
+type MentionService struct {
+	MentionRepository *repository.MentionRepository
+	AuthorService     *AuthorService
+	GrpcClient        *grpc_client.GrpcClient
+}
+
+var _ MentionServiceIn = &MentionService{}
+
+// Resolve finds the @usernames of a message and looks them up in user_service.
+// Usernames that don't belong to anybody are left as plain text. user_service resolves
+// one username per call, so the lookups run concurrently under a single deadline.
+func (ms *MentionService) Resolve(message string) ([]model.Mention, error) {
+	const op = "service.ResolveMentions"
+
+	candidates := parseMentions(message)
+	if len(candidates) == 0 {
+		return []model.Mention{}, nil
+	}
+	usernames := []string{}
+	for _, candidate := range candidates {
+		if !slices.Contains(usernames, candidate.Username) {
+			usernames = append(usernames, candidate.Username)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userIds := make([]int, len(usernames))
+	errs := make([]error, len(usernames))
+	var wg sync.WaitGroup
+	for i, username := range usernames {
+		wg.Add(1)
+		go func(i int, username string) {
+			defer wg.Done()
+			userIds[i], errs[i] = ms.GrpcClient.ResolveUsername(ctx, username)
+		}(i, username)
+	}
+	wg.Wait()
+
+	resolved := map[string]int{}
+	for i, username := range usernames {
+		if errs[i] != nil && !errors.Is(errs[i], grpc_client.ErrUserNotFound) {
+			return nil, fmt.Errorf("%s: %w", op, errs[i])
+		}
+		resolved[username] = userIds[i]
+	}
+	mentions := make([]model.Mention, 0, len(candidates))
+	for _, candidate := range candidates {
+		if resolved[candidate.Username] == 0 {
+			continue
+		}
+		candidate.UserId = resolved[candidate.Username]
+		mentions = append(mentions, candidate)
+	}
+	return mentions, nil
+}
+
+func (ms *MentionService) GetPostMentions(postIds []int) (map[int][]model.Mention, error) {
+	mentions, err := ms.MentionRepository.GetPostMentions(postIds)
+	if err != nil {
+		return nil, err
+	}
+	return mentions, ms.attachUsernames(mentions)
+}
+
+func (ms *MentionService) GetCommentMentions(commentIds []int) (map[int][]model.Mention, error) {
+	mentions, err := ms.MentionRepository.GetCommentMentions(commentIds)
+	if err != nil {
+		return nil, err
+	}
+	return mentions, ms.attachUsernames(mentions)
+}
+
+// attachUsernames fills the current usernames of the mentioned users from the local copy
+// of the authors. Mentions of users that don't exist anymore are dropped, their text
+// stays in the message as it was written.
+func (ms *MentionService) attachUsernames(mentions map[int][]model.Mention) error {
+	userIds := []int{}
+	for _, entityMentions := range mentions {
+		for _, mention := range entityMentions {
+			if !slices.Contains(userIds, mention.UserId) {
+				userIds = append(userIds, mention.UserId)
+			}
+		}
+	}
+	if len(userIds) == 0 {
+		return nil
+	}
+	authors, err := ms.AuthorService.GetAuthors(userIds)
+	if err != nil {
+		return err
+	}
+	for id, entityMentions := range mentions {
+		kept := make([]model.Mention, 0, len(entityMentions))
+		for _, mention := range entityMentions {
+			author, ok := authors[mention.UserId]
+			if !ok {
+				continue
+			}
+			mention.Username = author.Username
+			kept = append(kept, mention)
+		}
+		mentions[id] = kept
+	}
+	return nil
+}
+
+// parseMentions returns the @usernames of a message that could be usernames,
+// without their user ids, up to MaxMentionsPerMessage of them
+func parseMentions(message string) []model.Mention {
+	mentions := []model.Mention{}
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(message, -1) {
+		// A trailing dot or dash ends the sentence rather than the username
+		username := strings.TrimRight(message[match[2]:match[3]], ".-")
+		if len(username) < minUsernameLength || len(username) > maxUsernameLength {
+			continue
+		}
+		start := match[2] - 1
+		mentions = append(mentions, model.Mention{
+			Username: username,
+			Offset:   utf8.RuneCountInString(message[:start]),
+			Length:   utf8.RuneCountInString(username) + 1,
+		})
+		if len(mentions) == MaxMentionsPerMessage {
+			break
+		}
+	}
+	return mentions
+}
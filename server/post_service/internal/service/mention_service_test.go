@@ -0,0 +1,69 @@
+package service
+
+import (
+	"post_service/internal/model"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []model.Mention
+	}{
+		{
+			name:    "mention at the start",
+			message: "@johnny_doe hi",
+			want:    []model.Mention{{Username: "johnny_doe", Offset: 0, Length: 11}},
+		},
+		{
+			name:    "offsets count runes",
+			message: "ünï @джон_доу-",
+			want:    []model.Mention{{Username: "джон_доу", Offset: 4, Length: 9}},
+		},
+		{
+			name:    "trailing dot ends the sentence",
+			message: "thanks @johnny.doe.",
+			want:    []model.Mention{{Username: "johnny.doe", Offset: 7, Length: 11}},
+		},
+		{
+			name:    "several mentions",
+			message: "@alice_1 and 😀 @bobby_2",
+			want: []model.Mention{
+				{Username: "alice_1", Offset: 0, Length: 8},
+				{Username: "bobby_2", Offset: 15, Length: 8},
+			},
+		},
+		{
+			name:    "e-mail addresses aren't mentions",
+			message: "write to johnny@example.com",
+			want:    []model.Mention{},
+		},
+		{
+			name:    "too short to be a username",
+			message: "@bob",
+			want:    []model.Mention{},
+		},
+		{
+			name:    "too long to be a username",
+			message: "@" + strings.Repeat("a", maxUsernameLength+1),
+			want:    []model.Mention{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parseMentions(test.message); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseMentions(%q) = %+v, want %+v", test.message, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseMentionsStopsAtLimit(t *testing.T) {
+	message := strings.Repeat("@johnny_doe ", MaxMentionsPerMessage+5)
+	if got := parseMentions(message); len(got) != MaxMentionsPerMessage {
+		t.Errorf("parseMentions returned %d mentions, want %d", len(got), MaxMentionsPerMessage)
+	}
+}
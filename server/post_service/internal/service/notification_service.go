@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"shared/eventbus"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxNotificationActors is how many of the most recent actors a notification lists
+	MaxNotificationActors = 3
+	// notificationEventRetention is how long processed event ids are kept to detect redeliveries
+	notificationEventRetention = 7 * 24 * time.Hour
+)
+
+type NotificationServiceIn interface {
+	HandleEvent(ctx context.Context, envelope *eventbus.Envelope) error
+	GetNotifications(userId int, cursor int, limit int) ([]model.Notification, int, error)
+	CountUnread(userId int) (int, error)
+	MarkRead(userId int, notificationIds []int64) error
+}
+
+// NotificationService turns the events of user_service and post_service into
+// notifications of the users they involve
+type NotificationService struct {
+	NotificationRepository *repository.NotificationRepository
+	AuthorService          *AuthorService
+	// AvatarBaseUrl is the public address of user_service that avatar urls are built from
+	AvatarBaseUrl string
+}
+
+var _ NotificationServiceIn = &NotificationService{}
+
+// HandleEvent records the notifications an event produces, events that don't
+// notify anybody are ignored. Redelivered events are recognized by their id.
+func (ns *NotificationService) HandleEvent(ctx context.Context, envelope *eventbus.Envelope) error {
+	const op = "service.HandleEvent"
+
+	activities, err := notificationActivities(envelope)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// Nobody is notified about what they did themselves
+	activities = slices.DeleteFunc(activities, func(activity model.NotificationActivity) bool {
+		return activity.UserId == 0 || activity.UserId == activity.ActorId
+	})
+	if len(activities) == 0 {
+		return nil
+	}
+	if _, err = ns.NotificationRepository.AddActivities(envelope.Id, activities); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// notificationActivities lists who an event notifies. An event that can't be
+// decoded fails permanently, redelivering it wouldn't change its payload.
+func notificationActivities(envelope *eventbus.Envelope) ([]model.NotificationActivity, error) {
+	switch envelope.Type {
+	case model.UserFollowed:
+		var event model.FollowEvent
+		if err := envelope.Decode(&event); err != nil {
+			return nil, eventbus.Permanent(err)
+		}
+		return []model.NotificationActivity{
+			{UserId: event.FolloweeId, Kind: model.NotificationFollow, ActorId: event.FollowerId, OccurredAt: event.OccurredAt},
+		}, nil
+	case model.PostReacted:
+		var event model.ReactionEvent
+		if err := envelope.Decode(&event); err != nil {
+			return nil, eventbus.Permanent(err)
+		}
+		return []model.NotificationActivity{{
+			UserId: event.PostAuthorId, Kind: model.NotificationReaction, PostId: event.PostId,
+			ActorId: event.UserId, OccurredAt: event.OccurredAt,
+		}}, nil
+	case model.CommentCreated:
+		var event model.CommentEvent
+		if err := envelope.Decode(&event); err != nil {
+			return nil, eventbus.Permanent(err)
+		}
+		activities := []model.NotificationActivity{{
+			UserId: event.PostAuthorId, Kind: model.NotificationComment, PostId: event.PostId,
+			ActorId: event.UserId, OccurredAt: event.OccurredAt,
+		}}
+		// The author of the post hears about the reply as a comment already
+		if event.ParentAuthorId != event.PostAuthorId {
+			activities = append(activities, model.NotificationActivity{
+				UserId: event.ParentAuthorId, Kind: model.NotificationReply, PostId: event.PostId,
+				ActorId: event.UserId, OccurredAt: event.OccurredAt,
+			})
+		}
+		return activities, nil
+	case model.MentionCreated:
+		var event model.MentionEvent
+		if err := envelope.Decode(&event); err != nil {
+			return nil, eventbus.Permanent(err)
+		}
+		activities := make([]model.NotificationActivity, 0, len(event.MentionedUserIds))
+		for _, userId := range event.MentionedUserIds {
+			activities = append(activities, model.NotificationActivity{
+				UserId: userId, Kind: model.NotificationMention, PostId: event.PostId,
+				ActorId: event.UserId, OccurredAt: event.OccurredAt,
+			})
+		}
+		return activities, nil
+	}
+	return nil, nil
+}
+
+// GetNotifications returns a page of the notifications of a user, the most
+// recently active first, together with the cursor of the next page. A notification
+// that gets a new actor while the pages are read moves to the top of the list, so
+// the following pages skip it rather than returning it twice. It shows up again
+// on the first page.
+func (ns *NotificationService) GetNotifications(userId int, cursor int, limit int) ([]model.Notification, int, error) {
+	const op = "service.GetNotifications"
+
+	limit, err := normalizePage(cursor, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	notifications, err := ns.NotificationRepository.GetNotifications(userId, cursor, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	notifications, nextCursor := paginate(notifications, limit, func(notification model.Notification) int {
+		return notification.Seq
+	})
+	if err = ns.attachActors(notifications); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return notifications, nextCursor, nil
+}
+
+// attachActors fills the details of the most recent actors of the notifications
+func (ns *NotificationService) attachActors(notifications []model.Notification) error {
+	actorIds := []int{}
+	for _, notification := range notifications {
+		for _, actorId := range notification.ActorIds[:min(MaxNotificationActors, len(notification.ActorIds))] {
+			if !slices.Contains(actorIds, actorId) {
+				actorIds = append(actorIds, actorId)
+			}
+		}
+	}
+	if len(actorIds) == 0 {
+		return nil
+	}
+	authors, err := ns.AuthorService.GetAuthors(actorIds)
+	if err != nil {
+		return err
+	}
+	for i := range notifications {
+		notifications[i].Actors = []model.NotificationActor{}
+		for _, actorId := range notifications[i].ActorIds {
+			if len(notifications[i].Actors) == MaxNotificationActors {
+				break
+			}
+			author, ok := authors[actorId]
+			if !ok {
+				continue
+			}
+			notifications[i].Actors = append(notifications[i].Actors, model.NotificationActor{
+				UserId:      author.UserId,
+				Username:    author.Username,
+				DisplayName: author.DisplayName,
+				AvatarUrl: fmt.Sprintf("%s/userApi/users/%d/avatar?v=%d",
+					ns.AvatarBaseUrl, author.UserId, author.AvatarVersion),
+			})
+		}
+	}
+	return nil
+}
+
+func (ns *NotificationService) CountUnread(userId int) (int, error) {
+	const op = "service.CountUnread"
+
+	count, err := ns.NotificationRepository.CountUnread(userId)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// MarkRead marks notifications of a user as read, all of them when notificationIds is empty
+func (ns *NotificationService) MarkRead(userId int, notificationIds []int64) error {
+	const op = "service.MarkRead"
+
+	if _, err := ns.NotificationRepository.MarkRead(userId, notificationIds); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Run forgets old processed event ids every hour until ctx is cancelled
+func (ns *NotificationService) Run(ctx context.Context) {
+	const op = "service.NotificationService.Run"
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := ns.NotificationRepository.DeleteProcessedEvents(notificationEventRetention); err != nil {
+				logrus.WithField("op", op).Errorln(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"post_service/internal/model"
+	"shared/eventbus"
+	"testing"
+)
+
+func TestNotificationActivitiesOfMalformedEvents(t *testing.T) {
+	for _, eventType := range []string{model.UserFollowed, model.PostReacted, model.CommentCreated, model.MentionCreated} {
+		t.Run(eventType, func(t *testing.T) {
+			envelope := &eventbus.Envelope{Type: eventType, Version: 1, Data: json.RawMessage(`{"PostId": "one"}`)}
+			_, err := notificationActivities(envelope)
+			if !eventbus.IsPermanent(err) {
+				t.Fatalf("notificationActivities() error = %v, want a permanent error", err)
+			}
+		})
+	}
+}
+
+func TestNotificationActivitiesOfComments(t *testing.T) {
+	envelope, err := eventbus.NewEnvelope(EventSource, &model.CommentEvent{
+		Type: model.CommentCreated, PostId: 1, PostAuthorId: 2, ParentAuthorId: 3, UserId: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	activities, err := notificationActivities(envelope)
+	if err != nil {
+		t.Fatalf("notificationActivities() error = %v", err)
+	}
+	if len(activities) != 2 ||
+		activities[0].UserId != 2 || activities[0].Kind != model.NotificationComment ||
+		activities[1].UserId != 3 || activities[1].Kind != model.NotificationReply {
+		t.Fatalf("notificationActivities() = %+v, want a comment for the post author and a reply for the parent author", activities)
+	}
+}
+
+func TestHandleUserEventOfMalformedEvents(t *testing.T) {
+	authorService := &AuthorService{}
+	envelope := &eventbus.Envelope{Type: model.UserUpdated, Version: 1, Data: json.RawMessage(`{"UserId": "one"}`)}
+	if err := authorService.HandleUserEvent(context.Background(), envelope); !eventbus.IsPermanent(err) {
+		t.Fatalf("HandleUserEvent() error = %v, want a permanent error", err)
+	}
+}
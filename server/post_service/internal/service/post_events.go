@@ -0,0 +1,64 @@
+package service
+
+import (
+	"post_service/internal/amqp"
+	"post_service/internal/model"
+	"shared/eventbus"
+	"shared/outbox"
+	"slices"
+	"time"
+)
+
+// newOutboxMessage wraps an event in an envelope to be saved in the outbox and published to topic
+func newOutboxMessage(topic string, event eventbus.Event) (outbox.Message, error) {
+	return outbox.NewMessage(EventSource, topic, event)
+}
+
+// postEventMessages builds the outbox message announcing a change of a post on
+// the post_events exchange, it is saved in the transaction of the change
+func postEventMessages(eventType string, postId int, userId int, message string) ([]outbox.Message, error) {
+	outboxMessage, err := newOutboxMessage(amqp.PostEventsExchange, &model.PostEvent{
+		Type:       eventType,
+		PostId:     postId,
+		UserId:     userId,
+		Message:    message,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []outbox.Message{outboxMessage}, nil
+}
+
+// mentionEventMessages builds the outbox message telling the users of mentions
+// that they were mentioned, users in previous were told already
+func mentionEventMessages(
+	postId int,
+	commentId int,
+	userId int,
+	mentions []model.Mention,
+	previous []model.Mention,
+) ([]outbox.Message, error) {
+	mentionedUserIds := []int{}
+	for _, mention := range mentions {
+		wasMentioned := slices.ContainsFunc(previous, func(m model.Mention) bool { return m.UserId == mention.UserId })
+		if !wasMentioned && !slices.Contains(mentionedUserIds, mention.UserId) {
+			mentionedUserIds = append(mentionedUserIds, mention.UserId)
+		}
+	}
+	if len(mentionedUserIds) == 0 {
+		return nil, nil
+	}
+	outboxMessage, err := newOutboxMessage(amqp.PostEventsExchange, &model.MentionEvent{
+		Type:             model.MentionCreated,
+		PostId:           postId,
+		CommentId:        commentId,
+		UserId:           userId,
+		MentionedUserIds: mentionedUserIds,
+		OccurredAt:       time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []outbox.Message{outboxMessage}, nil
+}
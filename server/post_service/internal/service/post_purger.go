@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"post_service/internal/repository"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultPurgeInterval  = time.Hour
+	DefaultPurgeBatchSize = 500
+)
+
+// PostPurger removes the posts that were deleted more than PostRestoreWindow ago
+// for good, together with their comments, reactions, images and revisions
+type PostPurger struct {
+	PostRepository repository.PostRepositoryIn
+	Interval       time.Duration
+	BatchSize      int
+}
+
+// Run purges deleted posts every Interval until ctx is cancelled
+func (pp *PostPurger) Run(ctx context.Context) {
+	const op = "service.PostPurger.Run"
+	log := logrus.WithField("op", op)
+
+	ticker := time.NewTicker(pp.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Small batches keep the transactions short, keep going while full batches come back
+			for ctx.Err() == nil {
+				count, err := pp.PostRepository.PurgeDeletedPosts(PostRestoreWindow, pp.BatchSize)
+				if err != nil {
+					log.Errorln(err)
+					break
+				}
+				if count > 0 {
+					log.WithField("count", count).Info("purged deleted posts")
+				}
+				if count < int64(pp.BatchSize) {
+					break
+				}
+			}
+		}
+	}
+}
@@ -1,38 +1,192 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"post_service/internal/model"
 	"post_service/internal/repository"
+	"shared/outbox"
+	"slices"
+	"strings"
+	"time"
+
+	grpc_client "post_service/internal/clients/grpc"
 )
 
 type PostServiceIn interface {
-	GetPost(postId int) (*model.PostDb, error)
+	GetPost(postId int, viewerId int) (*model.PostDb, error)
+	GetFeed(
+		authorId int,
+		viewerId int,
+		cursor model.PostCursor,
+		limit int,
+		options model.PostListOptions,
+	) ([]model.PostDb, model.PostCursor, error)
+	GetTimeline(
+		userId int,
+		cursor model.PostCursor,
+		limit int,
+		options model.PostListOptions,
+	) ([]model.PostDb, model.PostCursor, error)
+	GetPostsByTag(
+		tag string,
+		viewerId int,
+		cursor model.PostCursor,
+		limit int,
+		options model.PostListOptions,
+	) ([]model.PostDb, model.PostCursor, error)
 	NewPost(message string, userId int) error
 	UpdatePost(postId int, newMessage string, userId int) error
 	DeletePost(postId int, userId int) error
+	RestorePost(postId int, userId int) error
+	GetPostRevisions(postId int, viewerId int) ([]model.PostRevision, error)
+	SearchPosts(query string, filter model.PostSearchFilter, viewerId int, offset int, limit int) ([]model.PostSearchResult, int, error)
 }
 
+const (
+	DefaultFeedLimit = 20
+	MaxFeedLimit     = 100
+	// MaxSearchQueryLength is the longest search query accepted, in bytes
+	MaxSearchQueryLength = 256
+	// PostRestoreWindow is how long a deleted post can be restored before it is purged
+	PostRestoreWindow = 30 * 24 * time.Hour
+)
+
 type PostService struct {
-	PostRepository *repository.PostRepository
+	PostRepository     repository.PostRepositoryIn
+	ReactionRepository repository.ReactionRepositoryIn
+	ImageRepository    repository.ImageRepositoryIn
+	TagRepository      repository.TagRepositoryIn
+	GrpcClient         *grpc_client.GrpcClient
+	AuthorService      AuthorServiceIn
+	MentionService     MentionServiceIn
+	// AvatarBaseUrl is the public address of user_service that avatar urls are built from
+	AvatarBaseUrl string
 }
 
 var _ PostServiceIn = &PostService{}
 
-func (p *PostService) GetPost(postId int) (*model.PostDb, error) {
+// GetPost returns a post together with its images, reaction counters and the reaction of viewerId
+func (p *PostService) GetPost(postId int, viewerId int) (*model.PostDb, error) {
 	const op = "service.GetPost"
 
 	postDb, err := p.PostRepository.GetPostById(postId)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return postDb, nil
+	posts := []model.PostDb{*postDb}
+	if err = p.attachDetails(posts, viewerId); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &posts[0], nil
+}
+
+// GetFeed returns a page of posts together with the cursor of the next page.
+// The returned cursor is zero when there are no more posts.
+func (p *PostService) GetFeed(
+	authorId int,
+	viewerId int,
+	cursor model.PostCursor,
+	limit int,
+	options model.PostListOptions,
+) ([]model.PostDb, model.PostCursor, error) {
+	const op = "service.GetFeed"
+
+	limit = normalizeLimit(limit)
+	if err := validateListOptions(options); err != nil {
+		return nil, model.PostCursor{}, err
+	}
+	// Fetch one extra row to find out whether another page exists
+	posts, err := p.PostRepository.GetPosts(authorId, cursor, limit+1, options)
+	if err != nil {
+		return nil, model.PostCursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+	posts, nextCursor := paginate(posts, limit, postCursor)
+	if err = p.attachDetails(posts, viewerId); err != nil {
+		return nil, model.PostCursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return posts, nextCursor, nil
+}
+
+// GetTimeline returns a page of the home timeline of userId: posts written by
+// the users they follow merged with their own posts
+func (p *PostService) GetTimeline(
+	userId int,
+	cursor model.PostCursor,
+	limit int,
+	options model.PostListOptions,
+) ([]model.PostDb, model.PostCursor, error) {
+	const op = "service.GetTimeline"
+
+	limit = normalizeLimit(limit)
+	if err := validateListOptions(options); err != nil {
+		return nil, model.PostCursor{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	authorIds, err := p.GrpcClient.GetFollowing(ctx, userId)
+	if err != nil {
+		return nil, model.PostCursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+	authorIds = append(authorIds, userId)
+
+	posts, err := p.PostRepository.GetPostsByAuthors(authorIds, cursor, limit+1, options)
+	if err != nil {
+		return nil, model.PostCursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+	posts, nextCursor := paginate(posts, limit, postCursor)
+	if err = p.attachDetails(posts, userId); err != nil {
+		return nil, model.PostCursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return posts, nextCursor, nil
+}
+
+// GetPostsByTag returns a page of the posts tagged with tag
+func (p *PostService) GetPostsByTag(
+	tag string,
+	viewerId int,
+	cursor model.PostCursor,
+	limit int,
+	options model.PostListOptions,
+) ([]model.PostDb, model.PostCursor, error) {
+	const op = "service.GetPostsByTag"
+
+	tag, ok := NormalizeTag(tag)
+	if !ok {
+		return nil, model.PostCursor{}, fmt.Errorf("invalid tag")
+	}
+	limit = normalizeLimit(limit)
+	if err := validateListOptions(options); err != nil {
+		return nil, model.PostCursor{}, err
+	}
+	posts, err := p.PostRepository.GetPostsByTag(tag, cursor, limit+1, options)
+	if err != nil {
+		return nil, model.PostCursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+	posts, nextCursor := paginate(posts, limit, postCursor)
+	if err = p.attachDetails(posts, viewerId); err != nil {
+		return nil, model.PostCursor{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return posts, nextCursor, nil
 }
 
 func (p *PostService) NewPost(message string, userId int) error {
 	const op = "service.NewPost"
 
-	err := p.PostRepository.NewPost(message, userId)
+	mentions, err := p.MentionService.Resolve(message)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = p.PostRepository.NewPost(message, userId, ParseHashtags(message), mentions,
+		func(postId int) ([]outbox.Message, error) {
+			messages, err := postEventMessages(model.PostCreated, postId, userId, message)
+			if err != nil {
+				return nil, err
+			}
+			mentionMessages, err := mentionEventMessages(postId, 0, userId, mentions, nil)
+			return append(messages, mentionMessages...), err
+		})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -49,7 +203,24 @@ func (p *PostService) UpdatePost(postId int, newMessage string, userId int) erro
 	if postDb.UserId != userId {
 		return fmt.Errorf("you are not an owner of this post")
 	}
-	err = p.PostRepository.UpdatePost(postId, newMessage)
+	mentions, err := p.MentionService.Resolve(newMessage)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	previousMentions, err := p.MentionService.GetPostMentions([]int{postId})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	messages, err := postEventMessages(model.PostUpdated, postId, userId, newMessage)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	mentionMessages, err := mentionEventMessages(postId, 0, userId, mentions, previousMentions[postId])
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	messages = append(messages, mentionMessages...)
+	err = p.PostRepository.UpdatePost(postId, newMessage, ParseHashtags(newMessage), mentions, messages)
 	if err != nil {
 		return fmt.Errorf(err.Error())
 	}
@@ -66,9 +237,263 @@ func (p *PostService) DeletePost(postId int, userId int) error {
 	if postDb.UserId != userId {
 		return fmt.Errorf("you are not an owner of this post")
 	}
-	err = p.PostRepository.DeletePost(postId)
+	messages, err := postEventMessages(model.PostDeleted, postId, userId, "")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = p.PostRepository.DeletePost(postId, messages)
 	if err != nil {
 		return fmt.Errorf(err.Error())
 	}
 	return nil
 }
+
+// RestorePost undoes the deletion of a post of userId within PostRestoreWindow
+func (p *PostService) RestorePost(postId int, userId int) error {
+	const op = "service.RestorePost"
+
+	messages, err := postEventMessages(model.PostRestored, postId, userId, "")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = p.PostRepository.RestorePost(postId, userId, PostRestoreWindow, messages)
+	if errors.Is(err, repository.ErrPostNotRestorable) {
+		return repository.ErrPostNotRestorable
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// GetPostRevisions returns the messages a post had before its edits, the most recent first.
+// Once the post is deleted only its owner viewerId can still see them.
+func (p *PostService) GetPostRevisions(postId int, viewerId int) ([]model.PostRevision, error) {
+	const op = "service.GetPostRevisions"
+
+	revisions, err := p.PostRepository.GetPostRevisions(postId, viewerId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return revisions, nil
+}
+
+// SearchPosts runs a full-text search over the messages of the posts. Results are
+// ranked, so they are paged by an offset, the number of results already returned.
+// The returned offset is 0 on the last page.
+func (p *PostService) SearchPosts(
+	query string,
+	filter model.PostSearchFilter,
+	viewerId int,
+	offset int,
+	limit int,
+) ([]model.PostSearchResult, int, error) {
+	const op = "service.SearchPosts"
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, fmt.Errorf("search query is required")
+	}
+	if len(query) > MaxSearchQueryLength {
+		return nil, 0, fmt.Errorf("search query is too long")
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, 0, fmt.Errorf("invalid date range")
+	}
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("invalid offset")
+	}
+	limit = normalizeLimit(limit)
+	results, err := p.PostRepository.SearchPosts(query, filter, offset, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	nextOffset := 0
+	if len(results) > limit {
+		results = results[:limit]
+		nextOffset = offset + limit
+	}
+
+	posts := make([]model.PostDb, len(results))
+	for i := range results {
+		posts[i] = results[i].Post
+	}
+	if err = p.attachDetails(posts, viewerId); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	for i := range results {
+		results[i].Post = posts[i]
+	}
+	return results, nextOffset, nil
+}
+
+// attachDetails fills the data of the posts that is stored outside of user_post
+func (p *PostService) attachDetails(posts []model.PostDb, viewerId int) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	if err := p.attachAuthors(posts); err != nil {
+		return err
+	}
+	if err := p.attachReactions(posts, viewerId); err != nil {
+		return err
+	}
+	if err := p.attachTags(posts); err != nil {
+		return err
+	}
+	if err := p.attachMentions(posts); err != nil {
+		return err
+	}
+	return p.attachImages(posts)
+}
+
+func (p *PostService) attachMentions(posts []model.PostDb) error {
+	postIds := make([]int, 0, len(posts))
+	for _, post := range posts {
+		postIds = append(postIds, post.PostId)
+	}
+	mentions, err := p.MentionService.GetPostMentions(postIds)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Mentions = mentions[posts[i].PostId]
+		if posts[i].Mentions == nil {
+			posts[i].Mentions = []model.Mention{}
+		}
+	}
+	return nil
+}
+
+func (p *PostService) attachTags(posts []model.PostDb) error {
+	postIds := make([]int, 0, len(posts))
+	for _, post := range posts {
+		postIds = append(postIds, post.PostId)
+	}
+	tags, err := p.TagRepository.GetPostTags(postIds)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Tags = tags[posts[i].PostId]
+		if posts[i].Tags == nil {
+			posts[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// attachAuthors fills the author details of the posts from the local copy of the authors
+func (p *PostService) attachAuthors(posts []model.PostDb) error {
+	authorIds := make([]int, 0, len(posts))
+	for _, post := range posts {
+		if !slices.Contains(authorIds, post.UserId) {
+			authorIds = append(authorIds, post.UserId)
+		}
+	}
+	authors, err := p.AuthorService.GetAuthors(authorIds)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		if author, ok := authors[posts[i].UserId]; ok {
+			posts[i].Username = author.Username
+			posts[i].DisplayName = author.DisplayName
+			posts[i].AvatarVersion = author.AvatarVersion
+		}
+		posts[i].AvatarUrl = fmt.Sprintf("%s/userApi/users/%d/avatar?v=%d",
+			p.AvatarBaseUrl, posts[i].UserId, posts[i].AvatarVersion)
+	}
+	return nil
+}
+
+func (p *PostService) attachImages(posts []model.PostDb) error {
+	postIds := make([]int, 0, len(posts))
+	for _, post := range posts {
+		postIds = append(postIds, post.PostId)
+	}
+	images, err := p.ImageRepository.GetPostImages(postIds)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Images = images[posts[i].PostId]
+		if posts[i].Images == nil {
+			posts[i].Images = []model.PostImage{}
+		}
+		for j := range posts[i].Images {
+			image := &posts[i].Images[j]
+			if image.Status == model.ImageStatusReady {
+				image.Url = fmt.Sprintf("/postApi/getImage?imageId=%d", image.ImageId)
+				image.ThumbnailUrl = fmt.Sprintf("/postApi/getImage?imageId=%d&size=thumbnail", image.ImageId)
+			}
+		}
+	}
+	return nil
+}
+
+// attachReactions fills the reaction counters of the posts and the reaction viewerId left on each of them
+func (p *PostService) attachReactions(posts []model.PostDb, viewerId int) error {
+	postIds := make([]int, 0, len(posts))
+	for _, post := range posts {
+		postIds = append(postIds, post.PostId)
+	}
+	counts, err := p.ReactionRepository.GetReactionCounts(postIds)
+	if err != nil {
+		return err
+	}
+	userReactions, err := p.ReactionRepository.GetUserReactions(postIds, viewerId)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].Reactions = counts[posts[i].PostId]
+		if posts[i].Reactions == nil {
+			posts[i].Reactions = map[string]int{}
+		}
+		posts[i].MyReaction = userReactions[posts[i].PostId]
+	}
+	return nil
+}
+
+func normalizePage(cursor int, limit int) (int, error) {
+	if cursor < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return normalizeLimit(limit), nil
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultFeedLimit
+	}
+	if limit > MaxFeedLimit {
+		return MaxFeedLimit
+	}
+	return limit
+}
+
+func validateListOptions(options model.PostListOptions) error {
+	if options.Sort != "" && options.Sort != model.SortNewest && options.Sort != model.SortOldest {
+		return fmt.Errorf("sort should be %s or %s", model.SortNewest, model.SortOldest)
+	}
+	if !options.From.IsZero() && !options.To.IsZero() && !options.From.Before(options.To) {
+		return fmt.Errorf("invalid date range")
+	}
+	return nil
+}
+
+// paginate trims a page fetched with limit+1 rows and returns the cursor of the next
+// page, taken from the last item kept, which is zero when there are no more items
+func paginate[T any, C any](items []T, limit int, cursor func(item T) C) ([]T, C) {
+	if len(items) <= limit {
+		var none C
+		return items, none
+	}
+	items = items[:limit]
+	return items, cursor(items[limit-1])
+}
+
+func postCursor(post model.PostDb) model.PostCursor {
+	return model.PostCursor{CreatedAt: post.CreatedAt, PostId: post.PostId}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakePostRepository lists its posts the way the queries of PostRepository do, the
+// methods the tests don't use panic through the nil interface
+type fakePostRepository struct {
+	repository.PostRepositoryIn
+
+	posts []model.PostDb
+	// limits are the limits the posts were asked for with
+	limits []int
+}
+
+func (f *fakePostRepository) GetPosts(
+	authorId int,
+	cursor model.PostCursor,
+	limit int,
+	options model.PostListOptions,
+) ([]model.PostDb, error) {
+	f.limits = append(f.limits, limit)
+
+	ranged := !options.From.IsZero() || !options.To.IsZero()
+	posts := []model.PostDb{}
+	for _, post := range f.posts {
+		switch {
+		case authorId != 0 && post.UserId != authorId:
+		case ranged && post.Legacy:
+		case !options.From.IsZero() && post.CreatedAt.Before(options.From):
+		case !options.To.IsZero() && !post.CreatedAt.Before(options.To):
+		default:
+			posts = append(posts, post)
+		}
+	}
+	// created before is the keyset order of the posts, oldest first
+	createdBefore := func(a, b model.PostDb) bool {
+		return a.CreatedAt.Before(b.CreatedAt) || a.CreatedAt.Equal(b.CreatedAt) && a.PostId < b.PostId
+	}
+	// comesBefore is the order of the list
+	comesBefore := func(a, b model.PostDb) bool {
+		if options.Sort == model.SortOldest {
+			return createdBefore(a, b)
+		}
+		return createdBefore(b, a)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return comesBefore(posts[i], posts[j])
+	})
+
+	page := []model.PostDb{}
+	last := model.PostDb{PostId: cursor.PostId, CreatedAt: cursor.CreatedAt}
+	for _, post := range posts {
+		if (cursor.IsZero() || comesBefore(last, post)) && len(page) < limit {
+			page = append(page, post)
+		}
+	}
+	return page, nil
+}
+
+type fakeAuthorService struct {
+	AuthorServiceIn
+}
+
+func (fakeAuthorService) GetAuthors(userIds []int) (map[int]model.Author, error) {
+	return map[int]model.Author{}, nil
+}
+
+type fakeReactionRepository struct {
+	repository.ReactionRepositoryIn
+}
+
+func (fakeReactionRepository) GetReactionCounts(postIds []int) (map[int]map[string]int, error) {
+	return map[int]map[string]int{}, nil
+}
+
+func (fakeReactionRepository) GetUserReactions(postIds []int, userId int) (map[int]string, error) {
+	return map[int]string{}, nil
+}
+
+type fakeTagRepository struct {
+	repository.TagRepositoryIn
+}
+
+func (fakeTagRepository) GetPostTags(postIds []int) (map[int][]string, error) {
+	return map[int][]string{}, nil
+}
+
+type fakeMentionService struct {
+	MentionServiceIn
+}
+
+func (fakeMentionService) GetPostMentions(postIds []int) (map[int][]model.Mention, error) {
+	return map[int][]model.Mention{}, nil
+}
+
+func newTestPostService(posts *fakePostRepository) *PostService {
+	return &PostService{
+		PostRepository:     posts,
+		ReactionRepository: fakeReactionRepository{},
+		ImageRepository:    &fakeImageRepository{},
+		TagRepository:      fakeTagRepository{},
+		AuthorService:      fakeAuthorService{},
+		MentionService:     fakeMentionService{},
+	}
+}
+
+// testPosts are six posts of two authors, 2 and 3 were created at the same time
+// and 1 is a legacy post
+func testPosts() []model.PostDb {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	return []model.PostDb{
+		{PostId: 1, UserId: 1, Legacy: true, CreatedAt: start},
+		{PostId: 3, UserId: 2, CreatedAt: start.Add(time.Hour)},
+		{PostId: 2, UserId: 1, CreatedAt: start.Add(time.Hour)},
+		{PostId: 4, UserId: 2, CreatedAt: start.Add(2 * time.Hour)},
+		// imported after post 5 was written, but created before it
+		{PostId: 6, UserId: 1, CreatedAt: start.Add(3 * time.Hour)},
+		{PostId: 5, UserId: 1, CreatedAt: start.Add(4 * time.Hour)},
+	}
+}
+
+// feedIds walks every page of a feed and returns the ids of the posts in the order they came
+func feedIds(t *testing.T, postService *PostService, authorId int, limit int, options model.PostListOptions) []int {
+	t.Helper()
+
+	ids := []int{}
+	cursor := model.PostCursor{}
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatalf("GetFeed() keeps returning a cursor, got %v so far", ids)
+		}
+		posts, next, err := postService.GetFeed(authorId, 0, cursor, limit, options)
+		if err != nil {
+			t.Fatalf("GetFeed() error = %v", err)
+		}
+		if len(posts) > limit {
+			t.Fatalf("GetFeed() returned %d posts, want at most %d", len(posts), limit)
+		}
+		for _, post := range posts {
+			ids = append(ids, post.PostId)
+		}
+		if next.IsZero() {
+			return ids
+		}
+		cursor = next
+	}
+}
+
+func TestGetFeedPages(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		authorId int
+		options  model.PostListOptions
+		want     []int
+	}{
+		{"newest first by default", 0, model.PostListOptions{}, []int{5, 6, 4, 3, 2, 1}},
+		{"newest first", 0, model.PostListOptions{Sort: model.SortNewest}, []int{5, 6, 4, 3, 2, 1}},
+		{"oldest first", 0, model.PostListOptions{Sort: model.SortOldest}, []int{1, 2, 3, 4, 6, 5}},
+		{"of an author", 1, model.PostListOptions{}, []int{5, 6, 2, 1}},
+		{
+			name:    "created in a range leaves legacy posts out",
+			options: model.PostListOptions{From: start, To: start.Add(3 * time.Hour)},
+			want:    []int{4, 3, 2},
+		},
+		{
+			name:    "created since leaves legacy posts out",
+			options: model.PostListOptions{Sort: model.SortOldest, From: start},
+			want:    []int{2, 3, 4, 6, 5},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, limit := range []int{1, 2, 4, 10} {
+				postService := newTestPostService(&fakePostRepository{posts: testPosts()})
+				if got := feedIds(t, postService, test.authorId, limit, test.options); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("pages of %d = %v, want %v", limit, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetFeedLimits(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, DefaultFeedLimit},
+		{-1, DefaultFeedLimit},
+		{5, 5},
+		{MaxFeedLimit + 1, MaxFeedLimit},
+	}
+	for _, test := range tests {
+		posts := &fakePostRepository{}
+		_, _, err := newTestPostService(posts).GetFeed(0, 0, model.PostCursor{}, test.limit, model.PostListOptions{})
+		if err != nil {
+			t.Fatalf("GetFeed() error = %v", err)
+		}
+		// One more post is fetched to find out whether another page exists
+		if len(posts.limits) != 1 || posts.limits[0] != test.want+1 {
+			t.Errorf("a limit of %d fetched %v posts, want %d", test.limit, posts.limits, test.want+1)
+		}
+	}
+}
+
+func TestGetFeedRejectsInvalidOptions(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		options model.PostListOptions
+	}{
+		{"unknown sort", model.PostListOptions{Sort: "popular"}},
+		{"empty range", model.PostListOptions{From: start, To: start}},
+		{"reversed range", model.PostListOptions{From: start.Add(time.Hour), To: start}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			posts := &fakePostRepository{posts: testPosts()}
+			if _, _, err := newTestPostService(posts).GetFeed(0, 0, model.PostCursor{}, 10, test.options); err == nil {
+				t.Fatal("GetFeed() error = nil, want an error")
+			}
+			if len(posts.limits) != 0 {
+				t.Error("GetFeed() queried the posts of invalid options")
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"fmt"
+	"post_service/internal/amqp"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"shared/outbox"
+	"time"
+)
+
+// Reactions is the fixed set of reactions a user can leave on a post
+var Reactions = map[string]bool{
+	"like":  true, // 👍
+	"love":  true, // ❤️
+	"haha":  true, // 😂
+	"wow":   true, // 😮
+	"sad":   true, // 😢
+	"angry": true, // 😡
+}
+
+type ReactionServiceIn interface {
+	AddReaction(postId int, reaction string, userId int) error
+	RemoveReaction(postId int, userId int) error
+}
+
+type ReactionService struct {
+	PostRepository     *repository.PostRepository
+	ReactionRepository *repository.ReactionRepository
+}
+
+var _ ReactionServiceIn = &ReactionService{}
+
+// AddReaction is idempotent, reacting again with the same reaction changes nothing
+// and reacting with another one replaces the previous reaction
+func (rs *ReactionService) AddReaction(postId int, reaction string, userId int) error {
+	const op = "service.AddReaction"
+
+	if reaction == "" {
+		reaction = "like"
+	}
+	if !Reactions[reaction] {
+		return fmt.Errorf("unknown reaction %q", reaction)
+	}
+	post, err := rs.PostRepository.GetPostById(postId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	message, err := newOutboxMessage(amqp.PostEventsExchange, &model.ReactionEvent{
+		Type:         model.PostReacted,
+		PostId:       postId,
+		PostAuthorId: post.UserId,
+		UserId:       userId,
+		Reaction:     reaction,
+		OccurredAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err = rs.ReactionRepository.AddReaction(postId, userId, reaction, []outbox.Message{message})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (rs *ReactionService) RemoveReaction(postId int, userId int) error {
+	const op = "service.RemoveReaction"
+
+	if _, err := rs.PostRepository.GetPostById(postId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	err := rs.ReactionRepository.RemoveReaction(postId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
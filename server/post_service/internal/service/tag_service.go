@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"post_service/internal/model"
+	"post_service/internal/repository"
+	"time"
+)
+
+type TagServiceIn interface {
+	GetTrendingTags(window string, limit int) ([]model.TrendingTag, error)
+}
+
+// TrendingWindow is a time window trending tags are computed over. HalfLife is how
+// long it takes for a use of a tag to count half as much as a use happening now.
+type TrendingWindow struct {
+	Length   time.Duration
+	HalfLife time.Duration
+}
+
+// TrendingWindows are the windows clients can ask trending tags for
+var TrendingWindows = map[string]TrendingWindow{
+	"hour": {Length: time.Hour, HalfLife: 15 * time.Minute},
+	"day":  {Length: 24 * time.Hour, HalfLife: 6 * time.Hour},
+}
+
+const (
+	DefaultTrendingWindow = "hour"
+	DefaultTrendingLimit  = 10
+	MaxTrendingLimit      = 50
+)
+
+type TagService struct {
+	TagRepository *repository.TagRepository
+}
+
+var _ TagServiceIn = &TagService{}
+
+// GetTrendingTags returns the tags that trend over the given window, an empty window means DefaultTrendingWindow
+func (ts *TagService) GetTrendingTags(window string, limit int) ([]model.TrendingTag, error) {
+	const op = "service.GetTrendingTags"
+
+	if window == "" {
+		window = DefaultTrendingWindow
+	}
+	trendingWindow, ok := TrendingWindows[window]
+	if !ok {
+		return nil, fmt.Errorf("unknown trending window %q", window)
+	}
+	if limit <= 0 {
+		limit = DefaultTrendingLimit
+	}
+	if limit > MaxTrendingLimit {
+		limit = MaxTrendingLimit
+	}
+	tags, err := ts.TagRepository.GetTrendingTags(time.Now().Add(-trendingWindow.Length), trendingWindow.HalfLife, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return tags, nil
+}
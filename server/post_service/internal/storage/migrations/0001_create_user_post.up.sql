@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS user_post(
+	id SERIAL PRIMARY KEY,
+	message TEXT NOT NULL,
+	user_id INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS user_post_user_id_idx ON user_post (user_id, id DESC);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS post_comment(
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES user_post (id) ON DELETE CASCADE,
+	parent_id INTEGER REFERENCES post_comment (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL,
+	message TEXT NOT NULL,
+	deleted BOOLEAN NOT NULL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS post_comment_post_id_idx ON post_comment (post_id, parent_id, id);
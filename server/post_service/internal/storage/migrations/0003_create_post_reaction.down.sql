@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS post_reaction_count;
+DROP TABLE IF EXISTS post_reaction;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS post_reaction(
+	post_id INTEGER NOT NULL REFERENCES user_post (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL,
+	reaction VARCHAR(16) NOT NULL,
+	PRIMARY KEY (post_id, user_id)
+);
+CREATE TABLE IF NOT EXISTS post_reaction_count(
+	post_id INTEGER NOT NULL REFERENCES user_post (id) ON DELETE CASCADE,
+	reaction VARCHAR(16) NOT NULL,
+	count INTEGER NOT NULL DEFAULT 0 CHECK (count >= 0),
+	PRIMARY KEY (post_id, reaction)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS post_image(
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES user_post (id) ON DELETE CASCADE,
+	status VARCHAR(16) NOT NULL,
+	image bytea,
+	thumbnail bytea,
+	width INTEGER NOT NULL DEFAULT 0,
+	height INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS post_image_post_id_idx ON post_image (post_id);
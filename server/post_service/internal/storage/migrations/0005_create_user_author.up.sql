@@ -0,0 +1,8 @@
+-- The copy of the authors of posts and comments, user_service owns the users
+CREATE TABLE IF NOT EXISTS user_author(
+	user_id INTEGER PRIMARY KEY,
+	username VARCHAR(255) NOT NULL,
+	display_name VARCHAR(64) NOT NULL DEFAULT '',
+	avatar_version INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMPTZ NOT NULL
+);
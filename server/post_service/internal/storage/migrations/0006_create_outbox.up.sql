@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS outbox(
+	id BIGSERIAL PRIMARY KEY,
+	topic VARCHAR(255) NOT NULL,
+	message_type VARCHAR(64) NOT NULL,
+	payload bytea NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	last_error TEXT,
+	sent_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (next_attempt_at, id) WHERE sent_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notification_event;
+DROP TABLE IF EXISTS notification;
+DROP SEQUENCE IF EXISTS notification_seq;
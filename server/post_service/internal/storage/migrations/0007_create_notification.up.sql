@@ -0,0 +1,20 @@
+CREATE SEQUENCE IF NOT EXISTS notification_seq;
+-- Unread notifications about the same thing are aggregated into one row,
+-- seq moves it to the top of the list whenever it gets a new actor
+CREATE TABLE IF NOT EXISTS notification(
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL,
+	kind VARCHAR(32) NOT NULL,
+	post_id INTEGER NOT NULL DEFAULT 0,
+	actor_ids INTEGER[] NOT NULL,
+	read BOOLEAN NOT NULL DEFAULT false,
+	seq BIGINT NOT NULL DEFAULT nextval('notification_seq'),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS notification_unread_idx ON notification (user_id, kind, post_id) WHERE NOT read;
+CREATE INDEX IF NOT EXISTS notification_user_seq_idx ON notification (user_id, seq DESC);
+CREATE TABLE IF NOT EXISTS notification_event(
+	event_id VARCHAR(64) PRIMARY KEY,
+	received_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS user_post_created_at_idx;
+DROP INDEX IF EXISTS user_post_search_idx;
+ALTER TABLE user_post DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE user_post DROP COLUMN IF EXISTS created_at;
+ALTER TABLE user_post DROP COLUMN IF EXISTS legacy;
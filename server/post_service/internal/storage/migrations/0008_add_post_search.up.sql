@@ -0,0 +1,11 @@
+-- Posts written before created_at was added get the time this migration runs, which
+-- says nothing about them. legacy flags them, it is true for the posts that exist now
+-- and false for every post written from here on.
+ALTER TABLE user_post ADD COLUMN IF NOT EXISTS legacy BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE user_post ALTER COLUMN legacy SET DEFAULT false;
+ALTER TABLE user_post ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+-- search_vector is kept up to date by the repository whenever the message is written
+ALTER TABLE user_post ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+UPDATE user_post SET search_vector = to_tsvector('english', message) WHERE search_vector IS NULL;
+CREATE INDEX IF NOT EXISTS user_post_search_idx ON user_post USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS user_post_created_at_idx ON user_post (created_at);
@@ -0,0 +1,9 @@
+-- Hashtags of the posts, created_at is when the tag was added to the post and drives trending tags
+CREATE TABLE IF NOT EXISTS post_tag(
+	post_id INTEGER NOT NULL REFERENCES user_post (id) ON DELETE CASCADE,
+	tag VARCHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	PRIMARY KEY (post_id, tag)
+);
+CREATE INDEX IF NOT EXISTS post_tag_tag_idx ON post_tag (tag, post_id DESC);
+CREATE INDEX IF NOT EXISTS post_tag_created_at_idx ON post_tag (created_at);
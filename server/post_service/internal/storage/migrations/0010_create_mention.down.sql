@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS comment_mention;
+DROP TABLE IF EXISTS post_mention;
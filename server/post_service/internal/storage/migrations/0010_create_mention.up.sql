@@ -0,0 +1,17 @@
+-- Mentions point at users by id and their username is looked up when they are read,
+-- so mentions of renamed users keep working. start_offset and length count the
+-- characters of the @username in the message.
+CREATE TABLE IF NOT EXISTS post_mention(
+	post_id INTEGER NOT NULL REFERENCES user_post (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL,
+	start_offset INTEGER NOT NULL,
+	length INTEGER NOT NULL,
+	PRIMARY KEY (post_id, start_offset)
+);
+CREATE TABLE IF NOT EXISTS comment_mention(
+	comment_id INTEGER NOT NULL REFERENCES post_comment (id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL,
+	start_offset INTEGER NOT NULL,
+	length INTEGER NOT NULL,
+	PRIMARY KEY (comment_id, start_offset)
+);
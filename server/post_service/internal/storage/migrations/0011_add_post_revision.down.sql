@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS post_revision;
+DROP INDEX IF EXISTS user_post_deleted_at_idx;
+ALTER TABLE user_post DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE user_post DROP COLUMN IF EXISTS edited_at;
@@ -0,0 +1,15 @@
+-- edited_at is set by the latest edit of the message, deleted_at marks posts
+-- that can still be restored until they are purged
+ALTER TABLE user_post ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+ALTER TABLE user_post ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS user_post_deleted_at_idx ON user_post (deleted_at) WHERE deleted_at IS NOT NULL;
+-- Every edit keeps the message it replaced, created_at is when that message was written.
+-- legacy is set when that is the unknown creation time of a legacy post.
+CREATE TABLE IF NOT EXISTS post_revision(
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES user_post (id) ON DELETE CASCADE,
+	message TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	legacy BOOLEAN NOT NULL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS post_revision_post_id_idx ON post_revision (post_id, id DESC);
@@ -0,0 +1 @@
+ALTER TABLE user_post DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,5 @@
+-- updated_at is set whenever the content of a post changes, created_at was added with post search
+ALTER TABLE user_post ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE user_post SET updated_at = coalesce(edited_at, created_at) WHERE updated_at IS NULL;
+ALTER TABLE user_post ALTER COLUMN updated_at SET DEFAULT now();
+ALTER TABLE user_post ALTER COLUMN updated_at SET NOT NULL;
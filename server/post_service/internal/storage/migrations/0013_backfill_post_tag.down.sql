@@ -0,0 +1,2 @@
+-- The backfilled tags can't be told apart from the ones written since, they are kept
+SELECT 1;
@@ -0,0 +1,20 @@
+-- Tags the posts written before post_tag existed. This follows ParseHashtags as close
+-- as Postgres regular expressions allow: a # that doesn't continue a word, then letters,
+-- digits and underscores, lowercased and in NFC, with a letter and at most 64 characters,
+-- at most 30 tags per post. Legacy posts get tags dated -infinity, so the backfill
+-- doesn't flood the trending tags.
+INSERT INTO post_tag (post_id, tag, created_at)
+SELECT post_id, tag, created_at FROM (
+	SELECT post_id, tag, created_at, row_number() OVER (PARTITION BY post_id ORDER BY position) AS n
+	FROM (
+		SELECT up.id AS post_id, lower(normalize(m.match[1], NFC)) AS tag,
+			CASE WHEN up.legacy THEN '-infinity' ELSE up.created_at END AS created_at, min(m.position) AS position
+		FROM user_post AS up,
+			regexp_matches(up.message, '(?:^|[^[:alnum:]_&/#])#([[:alnum:]_]+)', 'g') WITH ORDINALITY AS m(match, position)
+		WHERE up.deleted_at IS NULL
+		GROUP BY up.id, lower(normalize(m.match[1], NFC)), up.legacy, up.created_at
+	) AS tags
+	WHERE char_length(tag) <= 64 AND tag ~ '[[:alpha:]]'
+) AS numbered
+WHERE n <= 30
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS user_post_created_at_idx ON user_post (created_at);
+DROP INDEX IF EXISTS user_post_created_at_id_idx;
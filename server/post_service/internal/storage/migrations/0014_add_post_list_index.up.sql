@@ -0,0 +1,3 @@
+-- Post lists are ordered and paged by (created_at, id)
+CREATE INDEX IF NOT EXISTS user_post_created_at_id_idx ON user_post (created_at, id);
+DROP INDEX IF EXISTS user_post_created_at_idx;
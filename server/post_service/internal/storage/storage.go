@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"context"
+	"embed"
 	"fmt"
 	"os"
+	"shared/migrate"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
@@ -12,29 +15,52 @@ type Storage struct {
 	Db *sqlx.DB
 }
 
-const schema = `
-CREATE TABLE IF NOT EXISTS user_post(
-	id SERIAL PRIMARY KEY,
-	message TEXT NOT NULL,
-	user_id INTEGER REFERENCES app_user (id)
-);
-`
+//go:embed migrations/*.sql
+var migrations embed.FS
 
+// New connects to the database and brings its schema up to date
 func New() *Storage {
+	s := Open()
+	migrator, err := s.Migrator()
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		logrus.Fatalln("error while migrating storage", err)
+	}
+	return s
+}
+
+// Open connects to the database without touching its schema
+func Open() *Storage {
+	return open(os.Getenv("DB_NAME"))
+}
+
+// OpenLegacy connects to LEGACY_DB_NAME, the database post_service shared with
+// user_service before it got its own, on the same server as its database
+func OpenLegacy() *Storage {
+	return open(os.Getenv("LEGACY_DB_NAME"))
+}
+
+func open(dbName string) *Storage {
 	connectionString := fmt.Sprintf(
 		"user=%v password=%v dbname=%v port=%v host=host.docker.internal sslmode=disable",
-		os.Getenv("DB_USERNAME"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), os.Getenv("DB_PORT"),
+		os.Getenv("DB_USERNAME"), os.Getenv("DB_PASSWORD"), dbName, os.Getenv("DB_PORT"),
 	)
 	logrus.Infoln(connectionString)
 	db, err := sqlx.Connect("postgres", connectionString)
 	if err != nil {
 		logrus.Fatalln("error while creating storage", err)
 	}
-	db.MustExec(schema)
 
 	return &Storage{Db: db}
 }
 
+// Migrator loads the embedded migrations of the service
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	return migrate.New(s.Db, migrations, "migrations", "post_service")
+}
+
 func (s *Storage) Stop() {
 	err := s.Db.Close()
 	if err != nil {
@@ -0,0 +1,41 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+)
+
+// Publisher sends envelopes to a topic. A topic is either an exchange that
+// fans events out to the queues bound to it or, like the default exchange of
+// RabbitMQ, the name of the queue the envelope goes to.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, envelope *Envelope) error
+}
+
+// Handler processes an envelope, a returned error means it wasn't processed
+type Handler func(ctx context.Context, envelope *Envelope) error
+
+// Subscriber runs a handler on every envelope that arrives on a queue
+type Subscriber interface {
+	Subscribe(queue string, handler Handler) error
+}
+
+var ErrClosed = errors.New("event bus is closed")
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying won't fix, like a malformed message.
+// Subscribers don't redeliver such messages.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
@@ -0,0 +1,89 @@
+// Package eventbus is how the services exchange messages. Every message is an
+// Envelope around a typed, versioned event and travels through the Publisher
+// and Subscriber interfaces: RabbitMQ in production, Memory in tests.
+package eventbus
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Event is the payload of an envelope. The version is bumped whenever the
+// payload changes in a way consumers of the previous version can't read.
+type Event interface {
+	EventType() string
+	EventVersion() int
+}
+
+type Envelope struct {
+	Id         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	Source     string          `json:"source"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Data       json.RawMessage `json:"data"`
+}
+
+var (
+	ErrInvalidEnvelope    = errors.New("invalid envelope")
+	ErrUnexpectedType     = errors.New("unexpected event type")
+	ErrUnsupportedVersion = errors.New("unsupported event version")
+)
+
+// NewEnvelope wraps an event published by the service source
+func NewEnvelope(source string, event Event) (*Envelope, error) {
+	const op = "eventbus.NewEnvelope"
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &Envelope{
+		Id:         hex.EncodeToString(id),
+		Type:       event.EventType(),
+		Version:    event.EventVersion(),
+		Source:     source,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}, nil
+}
+
+// Parse reads an envelope from the body of a message
+func Parse(body []byte) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEnvelope, err)
+	}
+	if envelope.Type == "" || envelope.Version < 1 || len(envelope.Data) == 0 {
+		return nil, ErrInvalidEnvelope
+	}
+	return &envelope, nil
+}
+
+func (e *Envelope) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Decode reads the data of the envelope into event. It fails when the envelope
+// carries another type of event or a newer version than event knows about,
+// older versions are expected to stay readable.
+func (e *Envelope) Decode(event Event) error {
+	if e.Version > event.EventVersion() {
+		return fmt.Errorf("%w: %s v%d", ErrUnsupportedVersion, e.Type, e.Version)
+	}
+	if err := json.Unmarshal(e.Data, event); err != nil {
+		return fmt.Errorf("%s: %w", e.Type, err)
+	}
+	if event.EventType() != e.Type {
+		return fmt.Errorf("%w: %s is not %s", ErrUnexpectedType, e.Type, event.EventType())
+	}
+	return nil
+}
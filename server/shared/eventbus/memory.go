@@ -0,0 +1,194 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Memory is an in-process bus that runs the flows between services without a
+// broker, in tests. Envelopes go through JSON like on the wire and are handled
+// one at a time per queue, failed ones are recorded instead of being retried.
+type Memory struct {
+	mu        sync.Mutex
+	bindings  map[string][]string
+	queues    map[string]*memoryQueue
+	published map[string][]*Envelope
+	failures  []Failure
+	// pending counts the envelopes not handled yet, drained is closed when it drops to 0
+	pending int
+	drained chan struct{}
+	closed  chan struct{}
+}
+
+// Failure is an envelope a handler returned an error for
+type Failure struct {
+	Queue    string
+	Envelope *Envelope
+	Err      error
+}
+
+type memoryQueue struct {
+	messages [][]byte
+	handler  Handler
+	wake     chan struct{}
+}
+
+var (
+	_ Publisher  = &Memory{}
+	_ Subscriber = &Memory{}
+)
+
+func NewMemory() *Memory {
+	return &Memory{
+		bindings:  map[string][]string{},
+		queues:    map[string]*memoryQueue{},
+		published: map[string][]*Envelope{},
+		drained:   make(chan struct{}),
+		closed:    make(chan struct{}),
+	}
+}
+
+// Bind delivers the envelopes published to topic to queue as well. A topic
+// without bindings delivers to the queue of the same name.
+func (m *Memory) Bind(topic, queue string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bindings[topic] = append(m.bindings[topic], queue)
+}
+
+func (m *Memory) Publish(ctx context.Context, topic string, envelope *Envelope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body, err := envelope.Marshal()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.closed:
+		return ErrClosed
+	default:
+	}
+	m.published[topic] = append(m.published[topic], envelope)
+	queues, ok := m.bindings[topic]
+	if !ok {
+		queues = []string{topic}
+	}
+	for _, name := range queues {
+		q := m.queue(name)
+		q.messages = append(q.messages, body)
+		m.pending++
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe starts handling the envelopes of queue, including the ones
+// published before. A queue has a single subscriber.
+func (m *Memory) Subscribe(queue string, handler Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	q := m.queue(queue)
+	if q.handler != nil {
+		return fmt.Errorf("queue %s already has a subscriber", queue)
+	}
+	q.handler = handler
+	go m.deliver(queue, q)
+	return nil
+}
+
+// queue returns the queue called name, m.mu must be held
+func (m *Memory) queue(name string) *memoryQueue {
+	q, ok := m.queues[name]
+	if !ok {
+		q = &memoryQueue{wake: make(chan struct{}, 1)}
+		m.queues[name] = q
+	}
+	return q
+}
+
+func (m *Memory) deliver(name string, q *memoryQueue) {
+	for {
+		select {
+		case <-m.closed:
+			return
+		case <-q.wake:
+		}
+		for {
+			m.mu.Lock()
+			if len(q.messages) == 0 {
+				m.mu.Unlock()
+				break
+			}
+			body := q.messages[0]
+			q.messages = q.messages[1:]
+			m.mu.Unlock()
+
+			envelope, err := Parse(body)
+			if err == nil {
+				err = q.handler(context.Background(), envelope)
+			}
+			m.mu.Lock()
+			if err != nil {
+				m.failures = append(m.failures, Failure{Queue: name, Envelope: envelope, Err: err})
+			}
+			m.pending--
+			if m.pending == 0 {
+				close(m.drained)
+				m.drained = make(chan struct{})
+			}
+			m.mu.Unlock()
+		}
+	}
+}
+
+// Wait blocks until every published envelope is handled, including the ones
+// the handlers publish in turn. Envelopes of queues nobody subscribed to keep
+// it waiting until ctx is done.
+func (m *Memory) Wait(ctx context.Context) error {
+	for {
+		m.mu.Lock()
+		pending, drained := m.pending, m.drained
+		m.mu.Unlock()
+		if pending == 0 {
+			return nil
+		}
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Published returns the envelopes published to topic so far
+func (m *Memory) Published(topic string) []*Envelope {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Envelope(nil), m.published[topic]...)
+}
+
+// Failures returns the envelopes the handlers failed on so far
+func (m *Memory) Failures() []Failure {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Failure(nil), m.failures...)
+}
+
+// Close stops the delivery, envelopes that weren't handled yet are dropped
+func (m *Memory) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.closed:
+	default:
+		close(m.closed)
+	}
+}
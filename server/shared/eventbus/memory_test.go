@@ -0,0 +1,144 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type greeting struct {
+	Name string
+}
+
+func (greeting) EventType() string { return "greeting" }
+func (greeting) EventVersion() int { return 1 }
+
+func waitDrained(t *testing.T, bus *Memory) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+}
+
+func publish(t *testing.T, bus *Memory, topic, name string) {
+	t.Helper()
+	envelope, err := NewEnvelope("test", &greeting{Name: name})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	if err = bus.Publish(context.Background(), topic, envelope); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+}
+
+// collect subscribes to queue and records the names of the greetings it receives
+func collect(t *testing.T, bus *Memory, queue string) func() []string {
+	t.Helper()
+	var mu sync.Mutex
+	var names []string
+	err := bus.Subscribe(queue, func(ctx context.Context, envelope *Envelope) error {
+		var event greeting
+		if err := envelope.Decode(&event); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, event.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), names...)
+	}
+}
+
+func TestMemoryDeliversInOrderToTheQueueOfTheTopic(t *testing.T) {
+	bus := NewMemory()
+	defer bus.Close()
+
+	publish(t, bus, "queue", "first")
+	received := collect(t, bus, "queue")
+	publish(t, bus, "queue", "second")
+	waitDrained(t, bus)
+
+	got := received()
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("received %v, want [first second]", got)
+	}
+	if published := bus.Published("queue"); len(published) != 2 {
+		t.Fatalf("Published() returned %d envelopes, want 2", len(published))
+	}
+}
+
+func TestMemoryFansOutBoundTopics(t *testing.T) {
+	bus := NewMemory()
+	defer bus.Close()
+	bus.Bind("exchange", "first")
+	bus.Bind("exchange", "second")
+	first := collect(t, bus, "first")
+	second := collect(t, bus, "second")
+
+	publish(t, bus, "exchange", "hello")
+	waitDrained(t, bus)
+
+	if len(first()) != 1 || len(second()) != 1 {
+		t.Fatalf("received %v and %v, want one greeting on every bound queue", first(), second())
+	}
+}
+
+func TestMemoryRecordsFailures(t *testing.T) {
+	bus := NewMemory()
+	defer bus.Close()
+	errFailed := errors.New("failed")
+	err := bus.Subscribe("queue", func(ctx context.Context, envelope *Envelope) error {
+		return errFailed
+	})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	publish(t, bus, "queue", "hello")
+	waitDrained(t, bus)
+
+	failures := bus.Failures()
+	if len(failures) != 1 || failures[0].Queue != "queue" || !errors.Is(failures[0].Err, errFailed) {
+		t.Fatalf("Failures() = %v, want the failure of the queue", failures)
+	}
+}
+
+func TestMemoryWaitsForUnsubscribedQueues(t *testing.T) {
+	bus := NewMemory()
+	defer bus.Close()
+	publish(t, bus, "nobody", "hello")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := bus.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestMemoryRejectsSecondSubscriberAndClosedPublish(t *testing.T) {
+	bus := NewMemory()
+	collect(t, bus, "queue")
+	if err := bus.Subscribe("queue", func(context.Context, *Envelope) error { return nil }); err == nil {
+		t.Fatal("Subscribe() accepted a second subscriber")
+	}
+
+	bus.Close()
+	envelope, err := NewEnvelope("test", &greeting{Name: "late"})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	if err = bus.Publish(context.Background(), "queue", envelope); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish() error = %v, want %v", err, ErrClosed)
+	}
+}
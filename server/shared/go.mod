@@ -0,0 +1,11 @@
+module shared
+
+go 1.21.6
+
+require (
+	github.com/jmoiron/sqlx v1.3.5
+	github.com/rabbitmq/amqp091-go v1.10.0
+	github.com/sirupsen/logrus v1.9.3
+)
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
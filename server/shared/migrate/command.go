@@ -0,0 +1,54 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Run implements the migrate subcommand of the services: `migrate up` applies
+// pending migrations, `migrate down [n]` reverts the last n (1 by default) and
+// `migrate status` lists them
+func Run(ctx context.Context, migrator *Migrator, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		count, err := migrator.Up(ctx)
+		logrus.Infof("applied %d migrations", count)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				return fmt.Errorf("invalid number of migrations: %s", args[1])
+			}
+			steps = parsed
+		}
+		count, err := migrator.Down(ctx, steps)
+		logrus.Infof("reverted %d migrations", count)
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d %-30s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return errors.New("usage: migrate [up | down [n] | status]")
+	}
+}
@@ -0,0 +1,271 @@
+// Package migrate applies numbered SQL migrations and records them in a history
+// table. Migration files are named NNNN_name.up.sql and NNNN_name.down.sql,
+// every migration runs in its own transaction.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const historyTable = `CREATE TABLE IF NOT EXISTS %s(
+	version INTEGER PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	Db         *sqlx.DB
+	Migrations []Migration
+	// Table records the applied migrations
+	Table string
+	// LockKey identifies the advisory lock that keeps replicas from migrating at the same time
+	LockKey int64
+}
+
+// New loads the migrations found in dir of fsys. The history table and the
+// advisory lock are named after service, so services sharing a database
+// never mix up their migrations.
+func New(db *sqlx.DB, fsys fs.FS, dir string, service string) (*Migrator, error) {
+	const op = "migrate.New"
+
+	migrations, err := load(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(service))
+	return &Migrator{
+		Db:         db,
+		Migrations: migrations,
+		Table:      service + "_migrations",
+		LockKey:    int64(hash.Sum64()),
+	}, nil
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every migration that hasn't been applied yet and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	const op = "migrate.Up"
+
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.Migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err = inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, "INSERT INTO "+m.Table+" (version, name) VALUES ($1, $2)",
+					migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			logrus.WithFields(logrus.Fields{"version": migration.Version, "name": migration.Name}).
+				Info("applied migration")
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	const op = "migrate.Down"
+
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.Migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s can't be reverted", migration.Version, migration.Name)
+			}
+			err = inTx(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, "DELETE FROM "+m.Table+" WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			logrus.WithFields(logrus.Fields{"version": migration.Version, "name": migration.Name}).
+				Info("reverted migration")
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("%s: %w", op, err)
+	}
+	return count, nil
+}
+
+// Status lists every known migration and whether it is applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	const op = "migrate.Status"
+
+	statuses := make([]Status, 0, len(m.Migrations))
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.Migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return statuses, nil
+}
+
+// withLock runs fn holding the session level advisory lock of the migrator.
+// Session locks belong to a connection, so everything runs on a single one.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.Db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.LockKey); err != nil {
+		return err
+	}
+	defer func() {
+		// The lock is released with the connection anyway, use a fresh context
+		// so a cancelled ctx doesn't keep it held by a pooled connection
+		_, unlockErr := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", m.LockKey)
+		if unlockErr != nil {
+			logrus.WithField("op", "migrate.withLock").Errorln(unlockErr)
+		}
+	}()
+
+	if _, err = conn.ExecContext(ctx, fmt.Sprintf(historyTable, m.Table)); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM "+m.Table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,45 @@
+// Package outbox implements the transactional outbox of the services: events
+// are saved in the outbox table in the transaction of the change they announce
+// and a Relay publishes them to the event bus afterwards.
+package outbox
+
+import (
+	"database/sql"
+	"shared/eventbus"
+)
+
+// Message is an envelope saved in the outbox table together with the change
+// it announces, the relay publishes it to Topic afterwards
+type Message struct {
+	Id       int64
+	Topic    string
+	Type     string
+	Payload  []byte
+	Attempts int
+}
+
+// NewMessage wraps an event published by the service source in an envelope to
+// be saved in the outbox and published to topic
+func NewMessage(source, topic string, event eventbus.Event) (Message, error) {
+	envelope, err := eventbus.NewEnvelope(source, event)
+	if err != nil {
+		return Message{}, err
+	}
+	payload, err := envelope.Marshal()
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Topic: topic, Type: envelope.Type, Payload: payload}, nil
+}
+
+// Add saves messages in the outbox as part of tx
+func Add(tx *sql.Tx, messages []Message) error {
+	for _, message := range messages {
+		_, err := tx.Exec(`INSERT INTO outbox (topic, message_type, payload) VALUES ($1, $2, $3)`,
+			message.Topic, message.Type, message.Payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
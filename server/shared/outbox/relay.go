@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"context"
+	"shared/eventbus"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultInterval  = time.Second
+	DefaultBatchSize = 100
+	// maxRetryDelay caps the exponential backoff of messages that fail to publish
+	maxRetryDelay = 5 * time.Minute
+	// retention is how long sent messages are kept around for debugging
+	retention = 7 * 24 * time.Hour
+)
+
+// Relay publishes the envelopes saved in the outbox table to the event bus.
+// Delivery is at least once: a crash between publishing and marking a message
+// sent publishes it again, consumers have to tolerate duplicates.
+type Relay struct {
+	Repository RepositoryIn
+	Publisher  eventbus.Publisher
+	Interval   time.Duration
+	BatchSize  int
+}
+
+// Run relays messages until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	const op = "outbox.Relay.Run"
+	log := logrus.WithField("op", op)
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-cleanup.C:
+			if _, err := r.Repository.DeleteSent(retention); err != nil {
+				log.Errorln(err)
+			}
+		case <-ticker.C:
+			// Keep going while full batches come back, there is a backlog to catch up on
+			for ctx.Err() == nil {
+				count, err := r.Repository.ProcessPending(r.BatchSize, r.publish, retryDelay)
+				if err != nil {
+					log.Errorln(err)
+					break
+				}
+				if count < r.BatchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (r *Relay) publish(message Message) error {
+	envelope, err := eventbus.Parse(message.Payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = r.Publisher.Publish(ctx, message.Topic, envelope)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"id": message.Id, "attempts": message.Attempts + 1}).
+			Warnln("failed to publish outbox message: ", err)
+	}
+	return err
+}
+
+// retryDelay doubles the delay with every failed attempt, starting at a second
+func retryDelay(attempts int) time.Duration {
+	if attempts > 16 {
+		return maxRetryDelay
+	}
+	return min(time.Second<<(attempts-1), maxRetryDelay)
+}
@@ -0,0 +1,140 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"shared/eventbus"
+	"sync"
+	"testing"
+	"time"
+)
+
+type ping struct{}
+
+func (ping) EventType() string { return "ping" }
+func (ping) EventVersion() int { return 1 }
+
+// fakeRepository hands out its pending messages like the outbox table does
+type fakeRepository struct {
+	mu      sync.Mutex
+	pending []Message
+	batches []int
+	sent    []int64
+	retries map[int64]time.Duration
+}
+
+func (f *fakeRepository) ProcessPending(
+	limit int,
+	publish func(message Message) error,
+	retryIn func(attempts int) time.Duration,
+) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch := f.pending[:min(limit, len(f.pending))]
+	f.pending = f.pending[len(batch):]
+	f.batches = append(f.batches, len(batch))
+	for _, message := range batch {
+		if err := publish(message); err != nil {
+			f.retries[message.Id] = retryIn(message.Attempts + 1)
+		} else {
+			f.sent = append(f.sent, message.Id)
+		}
+	}
+	return len(batch), nil
+}
+
+func (f *fakeRepository) DeleteSent(olderThan time.Duration) (int64, error) {
+	return 0, nil
+}
+
+// failingPublisher fails the envelopes published to its down topic
+type failingPublisher struct {
+	*eventbus.Memory
+	down string
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, topic string, envelope *eventbus.Envelope) error {
+	if topic == p.down {
+		return errors.New("broker is down")
+	}
+	return p.Memory.Publish(ctx, topic, envelope)
+}
+
+func TestRelayPublishesPendingMessages(t *testing.T) {
+	repository := &fakeRepository{retries: map[int64]time.Duration{}}
+	for id := int64(1); id <= 5; id++ {
+		message, err := NewMessage("test", "up", &ping{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		message.Id = id
+		if id == 5 {
+			message.Topic, message.Attempts = "down", 2
+		}
+		repository.pending = append(repository.pending, message)
+	}
+	bus := eventbus.NewMemory()
+	defer bus.Close()
+	relay := Relay{
+		Repository: repository,
+		Publisher:  &failingPublisher{Memory: bus, down: "down"},
+		Interval:   time.Millisecond,
+		BatchSize:  2,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		repository.mu.Lock()
+		processed := len(repository.pending) == 0
+		repository.mu.Unlock()
+		if processed {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("the relay didn't process the outbox")
+		case <-time.After(time.Millisecond):
+		}
+	}
+	cancel()
+	<-done
+
+	if published := bus.Published("up"); len(published) != 4 {
+		t.Errorf("published %d envelopes, want 4", len(published))
+	}
+	if len(repository.sent) != 4 {
+		t.Errorf("marked %v sent, want the 4 published messages", repository.sent)
+	}
+	if delay := repository.retries[5]; delay != 4*time.Second {
+		t.Errorf("failed message retries in %v, want 4s after its third attempt", delay)
+	}
+	// Full batches are followed by the next one right away, in the same tick
+	if batches := repository.batches; len(batches) < 3 || batches[0] != 2 || batches[1] != 2 || batches[2] != 1 {
+		t.Errorf("processed batches %v, want [2 2 1 ...]", batches)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{9, 256 * time.Second},
+		{10, maxRetryDelay},
+		{100, maxRetryDelay},
+	}
+	for _, test := range tests {
+		if got := retryDelay(test.attempts); got != test.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type RepositoryIn interface {
+	ProcessPending(limit int, publish func(message Message) error, retryIn func(attempts int) time.Duration) (int, error)
+	DeleteSent(olderThan time.Duration) (int64, error)
+}
+
+// Repository reads and updates the outbox table of a service
+type Repository struct {
+	Db *sqlx.DB
+}
+
+var _ RepositoryIn = &Repository{}
+
+// ProcessPending hands up to limit due messages to publish, oldest first, and
+// records the outcome: published messages are marked sent, failed ones are
+// rescheduled retryIn(attempts) later. The rows stay locked until the batch is
+// done, relays of other replicas skip them instead of sending them twice.
+// It returns how many messages were handed to publish.
+func (r *Repository) ProcessPending(
+	limit int,
+	publish func(message Message) error,
+	retryIn func(attempts int) time.Duration,
+) (int, error) {
+	const op = "outbox.ProcessPending"
+
+	tx, err := r.Db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, topic, message_type, payload, attempts FROM outbox
+	 WHERE sent_at IS NULL AND next_attempt_at <= now()
+	 ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	messages := []Message{}
+	for rows.Next() {
+		var message Message
+		err = rows.Scan(&message.Id, &message.Topic, &message.Type, &message.Payload, &message.Attempts)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		messages = append(messages, message)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, message := range messages {
+		if publishErr := publish(message); publishErr != nil {
+			_, err = tx.Exec(`UPDATE outbox SET attempts = attempts + 1, last_error = $1,
+			 next_attempt_at = now() + $2 * interval '1 millisecond' WHERE id = $3`,
+				publishErr.Error(), retryIn(message.Attempts+1).Milliseconds(), message.Id)
+		} else {
+			_, err = tx.Exec("UPDATE outbox SET sent_at = now(), last_error = NULL WHERE id = $1", message.Id)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return len(messages), nil
+}
+
+// DeleteSent removes messages that were sent more than olderThan ago
+func (r *Repository) DeleteSent(olderThan time.Duration) (int64, error) {
+	const op = "outbox.DeleteSent"
+
+	result, err := r.Db.Exec("DELETE FROM outbox WHERE sent_at < now() - $1 * interval '1 millisecond'",
+		olderThan.Milliseconds())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return result.RowsAffected()
+}
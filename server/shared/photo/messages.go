@@ -0,0 +1,53 @@
+// Package photo holds the messages exchanged with photo_service. Its clients
+// and photo_service itself encode and decode them with these types, so the
+// services can't drift apart.
+package photo
+
+// CreatePhotoMessage asks for the default avatar of a newly registered user
+type CreatePhotoMessage struct {
+	UserId   int
+	Username string
+}
+
+func (CreatePhotoMessage) EventType() string { return "avatar.create_default" }
+func (CreatePhotoMessage) EventVersion() int { return 1 }
+
+// ProcessAvatarMessage carries an avatar uploaded by a user
+type ProcessAvatarMessage struct {
+	UserId int
+	Data   []byte
+}
+
+func (ProcessAvatarMessage) EventType() string { return "avatar.process" }
+func (ProcessAvatarMessage) EventVersion() int { return 1 }
+
+// AvatarGeneratedMessage is sent back by photo_service with every rendered size of an avatar keyed by size
+type AvatarGeneratedMessage struct {
+	UserId  int
+	Avatars map[int][]byte
+}
+
+func (AvatarGeneratedMessage) EventType() string { return "avatar.generated" }
+func (AvatarGeneratedMessage) EventVersion() int { return 1 }
+
+// ProcessImageMessage carries the raw upload of an image attached to a post
+type ProcessImageMessage struct {
+	ImageId int
+	Data    []byte
+}
+
+func (ProcessImageMessage) EventType() string { return "image.process" }
+func (ProcessImageMessage) EventVersion() int { return 1 }
+
+// ImageProcessedMessage is sent back by photo_service, Error is set when the upload was rejected
+type ImageProcessedMessage struct {
+	ImageId   int
+	Image     []byte
+	Thumbnail []byte
+	Width     int
+	Height    int
+	Error     string
+}
+
+func (ImageProcessedMessage) EventType() string { return "image.processed" }
+func (ImageProcessedMessage) EventVersion() int { return 1 }
@@ -0,0 +1,113 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"shared/eventbus"
+	"slices"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+// RequeueDelay is how long a consumer waits before it puts back a message that
+// failed with a transient error, so a failing dependency isn't hammered
+var RequeueDelay = time.Second
+
+// Bus is the event bus of a service on top of a Connection, every publish is
+// confirmed by the broker and every delivery is acked once it is handled.
+type Bus struct {
+	conn      *Connection
+	service   string
+	exchanges []string
+}
+
+var (
+	_ eventbus.Publisher  = &Bus{}
+	_ eventbus.Subscriber = &Bus{}
+)
+
+// NewBus publishes and consumes on conn for service. Envelopes published to
+// one of exchanges are routed by their type, any other topic is the name of
+// the queue the envelope goes to.
+func NewBus(conn *Connection, service string, exchanges ...string) *Bus {
+	return &Bus{conn: conn, service: service, exchanges: exchanges}
+}
+
+func (b *Bus) Publish(ctx context.Context, topic string, envelope *eventbus.Envelope) error {
+	body, err := envelope.Marshal()
+	if err != nil {
+		return err
+	}
+	exchange, routingKey := "", topic
+	if slices.Contains(b.exchanges, topic) {
+		exchange, routingKey = topic, envelope.Type
+	}
+	return b.conn.Publish(ctx,
+		exchange,
+		routingKey,
+		amqp.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqp.Persistent,
+			MessageId:    envelope.Id,
+			Type:         envelope.Type,
+			Timestamp:    envelope.OccurredAt,
+			Body:         body,
+		},
+	)
+}
+
+// Subscribe handles the envelopes of queue in the background, one at a time.
+// Handled deliveries are acked, the ones that aren't envelopes or failed
+// permanently (see eventbus.Permanent) are rejected, which dead-letters them
+// when the queue has a dead letter exchange, and the others are put back on
+// the queue after RequeueDelay.
+func (b *Bus) Subscribe(queue string, handler eventbus.Handler) error {
+	deliveries := b.conn.Consume(queue, b.service+"."+queue, false)
+	go func() {
+		for d := range deliveries {
+			settle(queue, d, Handle(d, handler))
+		}
+	}()
+	return nil
+}
+
+func (b *Bus) Close() {
+	b.conn.Close()
+}
+
+// Handle runs handler on the envelope of d and returns its outcome. Deliveries
+// that aren't envelopes and panicking handlers fail permanently instead of
+// crashing the consumer.
+func Handle(d amqp.Delivery, handler eventbus.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = eventbus.Permanent(fmt.Errorf("panic: %v", r))
+		}
+	}()
+	envelope, err := eventbus.Parse(d.Body)
+	if err != nil {
+		return eventbus.Permanent(err)
+	}
+	return handler(context.Background(), envelope)
+}
+
+func settle(queue string, d amqp.Delivery, err error) {
+	log := logrus.WithFields(logrus.Fields{"queue": queue, "messageId": d.MessageId})
+	var settleErr error
+	switch {
+	case err == nil:
+		settleErr = d.Ack(false)
+	case eventbus.IsPermanent(err):
+		log.Errorln("rejecting message: ", err)
+		settleErr = d.Nack(false, false)
+	default:
+		log.Warnln("requeueing message: ", err)
+		time.Sleep(RequeueDelay)
+		settleErr = d.Nack(false, true)
+	}
+	if settleErr != nil {
+		log.Errorln(settleErr)
+	}
+}
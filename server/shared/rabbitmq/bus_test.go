@@ -0,0 +1,98 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"shared/eventbus"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// acknowledger records how a delivery was settled
+type acknowledger struct {
+	acked, nacked, requeued bool
+}
+
+func (a *acknowledger) Ack(tag uint64, multiple bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *acknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.nacked, a.requeued = true, requeue
+	return nil
+}
+
+func (a *acknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+type ping struct{}
+
+func (ping) EventType() string { return "ping" }
+func (ping) EventVersion() int { return 1 }
+
+func TestSettle(t *testing.T) {
+	RequeueDelay = 0
+	envelope, err := eventbus.NewEnvelope("test", &ping{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := envelope.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		body    []byte
+		handler eventbus.Handler
+		want    acknowledger
+	}{
+		{
+			name:    "acks handled deliveries",
+			body:    body,
+			handler: func(context.Context, *eventbus.Envelope) error { return nil },
+			want:    acknowledger{acked: true},
+		},
+		{
+			name:    "rejects deliveries that aren't envelopes",
+			body:    []byte("not json"),
+			handler: func(context.Context, *eventbus.Envelope) error { return nil },
+			want:    acknowledger{nacked: true},
+		},
+		{
+			name: "rejects permanent failures",
+			body: body,
+			handler: func(context.Context, *eventbus.Envelope) error {
+				return eventbus.Permanent(errors.New("malformed"))
+			},
+			want: acknowledger{nacked: true},
+		},
+		{
+			name:    "rejects panicking handlers",
+			body:    body,
+			handler: func(context.Context, *eventbus.Envelope) error { panic("boom") },
+			want:    acknowledger{nacked: true},
+		},
+		{
+			name: "requeues transient failures",
+			body: body,
+			handler: func(context.Context, *eventbus.Envelope) error {
+				return errors.New("database is down")
+			},
+			want: acknowledger{nacked: true, requeued: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := &acknowledger{}
+			d := amqp.Delivery{Acknowledger: got, DeliveryTag: 1, Body: test.body}
+			settle("queue", d, Handle(d, test.handler))
+			if *got != test.want {
+				t.Errorf("settled as %+v, want %+v", *got, test.want)
+			}
+		})
+	}
+}
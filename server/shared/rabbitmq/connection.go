@@ -0,0 +1,281 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+var (
+	ErrClosed = errors.New("rabbitmq connection is closed")
+	ErrNacked = errors.New("message was not confirmed by the broker")
+)
+
+// Connection keeps a channel to RabbitMQ open. When the connection or the
+// channel goes away it dials again with exponential backoff, declares the
+// topology again and resumes its consumers, so callers keep using the same
+// Connection and the same delivery channels across broker restarts.
+type Connection struct {
+	url      string
+	prefetch int
+	topology func(ch *amqp.Channel) error
+
+	mu        sync.Mutex
+	channel   *amqp.Channel
+	ready     chan struct{}
+	consumers map[string]*consumer
+	closed    chan struct{}
+	done      chan struct{}
+}
+
+type consumer struct {
+	queue      string
+	autoAck    bool
+	deliveries chan amqp.Delivery
+	// forwarders counts the goroutines copying deliveries of a channel into deliveries
+	forwarders sync.WaitGroup
+}
+
+// Dial connects in the background and keeps reconnecting until Close is called.
+// A non zero prefetch sets the QoS of the channel, topology declares the
+// exchanges and queues the service uses and runs on every new channel.
+func Dial(url string, prefetch int, topology func(ch *amqp.Channel) error) *Connection {
+	c := &Connection{
+		url:       url,
+		prefetch:  prefetch,
+		topology:  topology,
+		ready:     make(chan struct{}),
+		consumers: map[string]*consumer{},
+		closed:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *Connection) run() {
+	const op = "rabbitmq.Connection.run"
+	log := logrus.WithField("op", op)
+	defer close(c.done)
+
+	delay := minReconnectDelay
+	for {
+		conn, ch, err := c.connect()
+		if err != nil {
+			log.WithField("retryIn", delay).Warnln("failed to connect to RabbitMQ: ", err)
+			if !c.sleep(delay) {
+				return
+			}
+			delay = min(delay*2, maxReconnectDelay)
+			continue
+		}
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		c.mu.Lock()
+		err = c.startConsumers(ch)
+		if err == nil {
+			c.channel = ch
+			close(c.ready)
+		}
+		c.mu.Unlock()
+		if err != nil {
+			// The queue may be missing or declared by a service that isn't up
+			// yet, the next connection retries the consumers
+			conn.Close()
+			log.WithField("retryIn", delay).Warnln("failed to resume consumers: ", err)
+			if !c.sleep(delay) {
+				return
+			}
+			delay = min(delay*2, maxReconnectDelay)
+			continue
+		}
+		delay = minReconnectDelay
+		log.Info("connected to RabbitMQ")
+
+		select {
+		case <-c.closed:
+			ch.Close()
+			conn.Close()
+			return
+		case err := <-connClosed:
+			log.Warnln("RabbitMQ connection closed: ", err)
+		case err := <-channelClosed:
+			log.Warnln("RabbitMQ channel closed: ", err)
+			conn.Close()
+		}
+
+		c.mu.Lock()
+		c.channel = nil
+		c.ready = make(chan struct{})
+		c.mu.Unlock()
+	}
+}
+
+// sleep waits for delay, it returns false when the connection is closed meanwhile
+func (c *Connection) sleep(delay time.Duration) bool {
+	select {
+	case <-c.closed:
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+func (c *Connection) connect() (*amqp.Connection, *amqp.Channel, error) {
+	conn, err := amqp.Dial(c.url)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, err := conn.Channel()
+	if err == nil {
+		err = ch.Confirm(false)
+	}
+	if err == nil && c.prefetch > 0 {
+		err = ch.Qos(c.prefetch, 0, false)
+	}
+	if err == nil {
+		err = c.topology(ch)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, ch, nil
+}
+
+// startConsumers consumes the queues of all consumers on ch, c.mu must be held
+func (c *Connection) startConsumers(ch *amqp.Channel) error {
+	for tag, consumer := range c.consumers {
+		if err := c.startConsumer(ch, tag, consumer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startConsumer consumes the queue of consumer on ch, c.mu must be held
+func (c *Connection) startConsumer(ch *amqp.Channel, tag string, consumer *consumer) error {
+	deliveries, err := ch.Consume(consumer.queue, tag, consumer.autoAck, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("consuming %s: %w", consumer.queue, err)
+	}
+	consumer.forwarders.Add(1)
+	go func() {
+		defer consumer.forwarders.Done()
+		for d := range deliveries {
+			consumer.deliveries <- d
+		}
+	}()
+	return nil
+}
+
+// Consume returns the deliveries of queue. The returned channel survives
+// reconnections, it is closed by Cancel or Close. Deliveries received before a
+// reconnection can't be acked anymore, the broker sends them again.
+func (c *Connection) Consume(queue, tag string, autoAck bool) <-chan amqp.Delivery {
+	consumer := &consumer{queue: queue, autoAck: autoAck, deliveries: make(chan amqp.Delivery)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consumers[tag] = consumer
+	if c.channel != nil {
+		if err := c.startConsumer(c.channel, tag, consumer); err != nil {
+			// Closing the channel makes run reconnect and retry the consumer with backoff
+			logrus.WithField("queue", queue).Warnln("failed to consume, reconnecting: ", err)
+			c.channel.Close()
+		}
+	}
+	return consumer.deliveries
+}
+
+// Cancel stops the consumer tag. Its delivery channel is closed once the
+// deliveries the broker already sent are received.
+func (c *Connection) Cancel(tag string) error {
+	c.mu.Lock()
+	consumer, ok := c.consumers[tag]
+	delete(c.consumers, tag)
+	ch := c.channel
+	c.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	var err error
+	if ch != nil {
+		err = ch.Cancel(tag, false)
+	}
+	go func() {
+		consumer.forwarders.Wait()
+		close(consumer.deliveries)
+	}()
+	return err
+}
+
+// Publish sends a message once the channel is up and waits for the broker to confirm it
+func (c *Connection) Publish(ctx context.Context, exchange, routingKey string, message amqp.Publishing) error {
+	ch, err := c.waitChannel(ctx)
+	if err != nil {
+		return err
+	}
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, message)
+	if err != nil {
+		return err
+	}
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+func (c *Connection) waitChannel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		c.mu.Lock()
+		ch, ready := c.channel, c.ready
+		c.mu.Unlock()
+		if ch != nil {
+			return ch, nil
+		}
+		select {
+		case <-ready:
+		case <-c.closed:
+			return nil, ErrClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close stops reconnecting and closes the connection and the delivery channels of the consumers
+func (c *Connection) Close() {
+	select {
+	case <-c.closed:
+		return
+	default:
+		close(c.closed)
+	}
+	<-c.done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for tag, stopped := range c.consumers {
+		delete(c.consumers, tag)
+		go func(stopped *consumer) {
+			stopped.forwarders.Wait()
+			close(stopped.deliveries)
+		}(stopped)
+	}
+}
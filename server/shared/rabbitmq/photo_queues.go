@@ -0,0 +1,115 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// The queues of photo_service. Every service that publishes to or consumes
+// them declares them with DeclarePhotoQueues: RabbitMQ refuses to declare an
+// existing queue again with other arguments.
+const (
+	// PhotoQueue receives newly registered users, photo_service generates their default avatar
+	PhotoQueue = "photo_queue"
+	// AvatarProcessQueue receives avatars uploaded by users, photo_service renders them in every size
+	AvatarProcessQueue = "avatar_process_queue"
+	// AvatarResultQueue receives the avatars rendered by photo_service
+	AvatarResultQueue = "avatar_result_queue"
+	// ImageProcessQueue receives the images attached to posts
+	ImageProcessQueue = "image_process_queue"
+	// ImageResultQueue receives the images processed by photo_service
+	ImageResultQueue = "image_result_queue"
+	// PhotoDeadLetterExchange receives the messages of the work queues that
+	// can't be processed, they end up in the <queue>.dead queue
+	PhotoDeadLetterExchange = "photo_service.dlx"
+)
+
+// PhotoWorkQueues are the queues photo_service consumes
+var PhotoWorkQueues = []string{PhotoQueue, AvatarProcessQueue, ImageProcessQueue}
+
+// PhotoResultQueues are the queues photo_service publishes its results to
+var PhotoResultQueues = []string{AvatarResultQueue, ImageResultQueue}
+
+// PhotoRetryDelays are the delays before a message that failed with a transient
+// error is delivered again, one retry queue is declared per delay
+var PhotoRetryDelays = []time.Duration{time.Second, 10 * time.Second, time.Minute}
+
+// PhotoWorkQueueArgs are the arguments of the work queues of photo_service
+var PhotoWorkQueueArgs = amqp.Table{"x-dead-letter-exchange": PhotoDeadLetterExchange}
+
+// RetryQueueName is the queue a message of queue waits in before its attempt
+func RetryQueueName(queue string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, attempt)
+}
+
+// DeclarePhotoQueues declares the dead letter exchange of photo_service, its
+// work queues together with their retry and dead letter queues and its result
+// queues. All of them are durable, the messages are published persistent.
+func DeclarePhotoQueues(ch *amqp.Channel) error {
+	err := ch.ExchangeDeclare(
+		PhotoDeadLetterExchange,
+		amqp.ExchangeDirect,
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating exchange: %w", err)
+	}
+	for _, queue := range PhotoWorkQueues {
+		if err = declarePhotoWorkQueue(ch, queue); err != nil {
+			return err
+		}
+	}
+	for _, queue := range PhotoResultQueues {
+		if _, err = ch.QueueDeclare(queue, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("error creating queue: %w", err)
+		}
+	}
+	return nil
+}
+
+// declarePhotoWorkQueue declares a durable work queue together with its retry
+// queues, which send expired messages back to it, and its dead letter queue
+func declarePhotoWorkQueue(ch *amqp.Channel, name string) error {
+	_, err := ch.QueueDeclare(name, true, false, false, false, PhotoWorkQueueArgs)
+	if err != nil {
+		return fmt.Errorf("error creating queue: %w", err)
+	}
+	for attempt, delay := range PhotoRetryDelays {
+		_, err = ch.QueueDeclare(RetryQueueName(name, attempt+1), true, false, false, false, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": name,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating queue: %w", err)
+		}
+	}
+	dead, err := ch.QueueDeclare(name+".dead", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("error creating queue: %w", err)
+	}
+	if err = ch.QueueBind(dead.Name, name, PhotoDeadLetterExchange, false, nil); err != nil {
+		return fmt.Errorf("error binding queue: %w", err)
+	}
+	return nil
+}
+
+// UpgradePhotoQueues brings the queues declared by older releases, which
+// weren't durable and, for the work queues, had no dead letter exchange, to the
+// current declaration, see UpgradeQueues
+func UpgradePhotoQueues(url string) ([]string, error) {
+	queues := []Queue{}
+	for _, queue := range PhotoWorkQueues {
+		queues = append(queues, Queue{Name: queue, Args: PhotoWorkQueueArgs})
+	}
+	for _, queue := range PhotoResultQueues {
+		queues = append(queues, Queue{Name: queue})
+	}
+	return UpgradeQueues(url, queues, DeclarePhotoQueues)
+}
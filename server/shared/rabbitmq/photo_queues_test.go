@@ -0,0 +1,9 @@
+package rabbitmq
+
+import "testing"
+
+func TestRetryQueueName(t *testing.T) {
+	if got := RetryQueueName(PhotoQueue, 2); got != "photo_queue.retry.2" {
+		t.Errorf("RetryQueueName() = %q, want photo_queue.retry.2", got)
+	}
+}
@@ -0,0 +1,80 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Queue is a durable queue with the arguments it is declared with
+type Queue struct {
+	Name string
+	Args amqp.Table
+}
+
+// UpgradeQueues brings queues declared by older releases with other arguments,
+// like non durable ones, to their current declaration. RabbitMQ can't change a
+// queue in place: an outdated queue is deleted when it is empty and declared
+// again by declare, one that still holds messages is reported and has to be
+// drained first. It returns the names of the upgraded queues.
+func UpgradeQueues(url string, queues []Queue, declare func(ch *amqp.Channel) error) ([]string, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	upgraded := []string{}
+	for _, queue := range queues {
+		outdated, err := isOutdated(conn, queue)
+		if err != nil {
+			return upgraded, err
+		}
+		if !outdated {
+			continue
+		}
+		if err = deleteIfEmpty(conn, queue.Name); err != nil {
+			return upgraded, fmt.Errorf("%s can't be upgraded, drain it first: %w", queue.Name, err)
+		}
+		upgraded = append(upgraded, queue.Name)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return upgraded, err
+	}
+	defer ch.Close()
+	return upgraded, declare(ch)
+}
+
+// isOutdated declares queue on a channel of its own, the broker answers
+// PRECONDITION_FAILED and closes the channel when the queue exists with other arguments
+func isOutdated(conn *amqp.Connection, queue Queue) (bool, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return false, err
+	}
+	_, err = ch.QueueDeclare(queue.Name, true, false, false, false, queue.Args)
+	if isPreconditionFailed(err) {
+		return true, nil
+	}
+	ch.Close()
+	return false, err
+}
+
+func deleteIfEmpty(conn *amqp.Connection, queue string) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	if _, err = ch.QueueDelete(queue, false, true, false); err != nil {
+		return err
+	}
+	return ch.Close()
+}
+
+func isPreconditionFailed(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed
+}
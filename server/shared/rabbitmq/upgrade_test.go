@@ -0,0 +1,30 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestIsPreconditionFailed(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"queue declared with other arguments", &amqp.Error{Code: amqp.PreconditionFailed}, true},
+		{"wrapped", fmt.Errorf("declaring: %w", &amqp.Error{Code: amqp.PreconditionFailed}), true},
+		{"other broker error", &amqp.Error{Code: amqp.NotFound}, false},
+		{"other error", errors.New("connection refused"), false},
+		{"no error", nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := isPreconditionFailed(test.err); got != test.want {
+				t.Errorf("isPreconditionFailed(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
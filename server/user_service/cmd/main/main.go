@@ -2,15 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"shared/migrate"
+	"shared/outbox"
 	"strconv"
 	"syscall"
 	"time"
 	"user_service/internal/amqp"
 	"user_service/internal/handlers"
+	"user_service/internal/keys"
 	"user_service/internal/metrics"
 	"user_service/internal/repository"
 	"user_service/internal/services"
@@ -29,6 +34,28 @@ func main() {
 		logrus.Fatalln(err)
 	}
 
+	// `main migrate ...` manages the database schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(storage.Open(), os.Args[2:]); err != nil {
+			logrus.Fatalln(err)
+		}
+		return
+	}
+
+	// `main keys ...` manages the token signing keys and exits
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeys(os.Args[2:]); err != nil {
+			logrus.Fatalln(err)
+		}
+		return
+	}
+
+	// Init token signing keys
+	keySet, err := loadKeySet()
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+
 	// Init Db
 	storage := storage.New()
 
@@ -37,19 +64,34 @@ func main() {
 
 	// Init Handler
 	userRepository := repository.UserRepository{Db: storage.Db}
+	sessionRepository := repository.SessionRepository{Db: storage.Db}
 	userService := services.UserService{
-		UserRepository: &userRepository,
-		JwtSecretKey:   os.Getenv("JWT_SECRET_KEY"),
-		Amqp:           amqp_handler,
+		UserRepository:    &userRepository,
+		SessionRepository: &sessionRepository,
+		Keys:              keySet,
+		AccessTokenTTL:    durationFromEnv("ACCESS_TOKEN_TTL", services.DefaultAccessTokenTTL),
+		RefreshTokenTTL:   durationFromEnv("REFRESH_TOKEN_TTL", services.DefaultRefreshTokenTTL),
+		Publisher:         amqp_handler,
+	}
+	followRepository := repository.FollowRepository{Db: storage.Db}
+	followService := services.FollowService{
+		FollowRepository: &followRepository,
+		UserRepository:   &userRepository,
+	}
+	profileService := services.ProfileService{UserRepository: &userRepository}
+	handler := handlers.HttpHandler{
+		UserService:    userService,
+		FollowService:  followService,
+		ProfileService: profileService,
+		Keys:           keySet,
 	}
-	handler := handlers.HttpHandler{UserService: userService}
 
 	// Run gRPC Handler
 	grpcPort, err := strconv.ParseInt(os.Getenv("GRPC_PORT"), 10, 0)
 	if err != nil {
 		logrus.Fatalln(err)
 	}
-	grpcHandler := handlers.NewGrpcHandler(int(grpcPort), os.Getenv("JWT_SECRET_KEY"))
+	grpcHandler := handlers.NewGrpcHandler(int(grpcPort), &userService, &followService, &profileService)
 	go grpcHandler.MustRun()
 
 	// Run Http Server
@@ -62,6 +104,25 @@ func main() {
 	}
 	go server.ListenAndServe()
 
+	// Relay outbox messages to RabbitMQ
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	relayDone := make(chan struct{})
+	outboxRelay := outbox.Relay{
+		Repository: &outbox.Repository{Db: storage.Db},
+		Publisher:  amqp_handler,
+		Interval:   outbox.DefaultInterval,
+		BatchSize:  outbox.DefaultBatchSize,
+	}
+	go func() {
+		outboxRelay.Run(relayCtx)
+		close(relayDone)
+	}()
+
+	// Consume avatars rendered by photo_service
+	if err = amqp_handler.Subscribe(amqp.AvatarResultQueue, userService.HandleGeneratedAvatar); err != nil {
+		logrus.Fatalln(err)
+	}
+
 	// Run metrics server
 	go func() {
 		_ = metrics.Listen(":9080")
@@ -79,8 +140,80 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logrus.Fatalf("HTTP Server shutdown error")
 	}
+	stopRelay()
+	<-relayDone
 	storage.Stop()
 	amqp_handler.Close()
 
 	logrus.Info("application stopped")
 }
+
+// runMigrate implements the migrate subcommand, see migrate.Run
+func runMigrate(s *storage.Storage, args []string) error {
+	defer s.Stop()
+	migrator, err := s.Migrator()
+	if err != nil {
+		return err
+	}
+	return migrate.Run(context.Background(), migrator, args)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	return duration
+}
+
+// loadKeySet picks the token signing keys: PEM keys from JWT_KEYS_DIR if set,
+// otherwise the shared JWT_SECRET_KEY for HS256. An asymmetric JWT_SIGNING_ALG needs
+// JWT_KEYS_DIR, unless JWT_EPHEMERAL_KEYS=true asks for an in-memory key for development,
+// whose tokens become invalid on every restart.
+func loadKeySet() (*keys.KeySet, error) {
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		return keys.LoadDir(dir)
+	}
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	if alg == "" || alg == "HS256" {
+		return keys.NewHMAC(os.Getenv("JWT_SECRET_KEY"))
+	}
+	if os.Getenv("JWT_EPHEMERAL_KEYS") != "true" {
+		return nil, fmt.Errorf("JWT_SIGNING_ALG=%s needs JWT_KEYS_DIR, or JWT_EPHEMERAL_KEYS=true for development", alg)
+	}
+	logrus.Warnf("JWT_KEYS_DIR is not set, generating an ephemeral %s key", alg)
+	return keys.Generate(alg)
+}
+
+// runKeys implements the keys subcommand, which writes keys of JWT_SIGNING_ALG to
+// JWT_KEYS_DIR: `keys init` creates a key unless the directory already has one and
+// `keys rotate` adds a new key that becomes the active one on the next start
+func runKeys(args []string) error {
+	dir, alg := os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SIGNING_ALG")
+	if dir == "" || alg == "" {
+		return errors.New("keys needs JWT_KEYS_DIR and JWT_SIGNING_ALG")
+	}
+	if len(args) != 1 || (args[0] != "init" && args[0] != "rotate") {
+		return errors.New("usage: keys [init | rotate]")
+	}
+	if args[0] == "init" {
+		exists, err := keys.HasKeys(dir)
+		if err != nil {
+			return err
+		}
+		if exists {
+			logrus.Infof("%s already has a key", dir)
+			return nil
+		}
+	}
+	path, err := keys.GenerateFile(dir, alg)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("generated %s", path)
+	return nil
+}
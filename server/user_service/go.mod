@@ -2,6 +2,10 @@ module user_service
 
 go 1.21.6
 
+require shared v0.0.0
+
+replace shared => ../shared
+
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/bytedance/sonic v1.11.2 // indirect
@@ -2,29 +2,44 @@ package grpc_service
 
 import (
 	"context"
+	"errors"
+	"user_service/internal/models"
+	"user_service/internal/repository"
+	"user_service/internal/services"
 	authv1 "user_service/pkg/grpc/auth"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 )
 
+// MaxBatchGetUsers limits the number of ids a single BatchGetUsers call may ask for
+const MaxBatchGetUsers = 500
+
 type serverAPI struct {
 	authv1.UnimplementedAuthServer
-	JwtSecretKey string
+	UserService    services.UserServiceIn
+	FollowService  services.FollowServiceIn
+	ProfileService services.ProfileServiceIn
 }
 
-func Register(gRPC *grpc.Server, jwtSecretKey string) {
-	authv1.RegisterAuthServer(gRPC, &serverAPI{JwtSecretKey: jwtSecretKey})
+func Register(
+	gRPC *grpc.Server,
+	userService services.UserServiceIn,
+	followService services.FollowServiceIn,
+	profileService services.ProfileServiceIn,
+) {
+	authv1.RegisterAuthServer(gRPC, &serverAPI{
+		UserService:    userService,
+		FollowService:  followService,
+		ProfileService: profileService,
+	})
 }
 
 func (s *serverAPI) Authenticate(
 	ctx context.Context,
 	req *authv1.AuthenticateRequest,
 ) (*authv1.AuthenticateResponse, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(req.GetToken(), claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.JwtSecretKey), nil
-	})
+	userId, username, err := s.UserService.GetDataFromToken(req.GetToken())
 	if err != nil {
 		return &authv1.AuthenticateResponse{
 			StatusCode: 401,
@@ -33,7 +48,133 @@ func (s *serverAPI) Authenticate(
 
 	return &authv1.AuthenticateResponse{
 		StatusCode: 200,
-		UserId:     int64(claims["userId"].(float64)),
-		Username:   claims["username"].(string),
+		UserId:     int64(userId),
+		Username:   username,
+	}, nil
+}
+
+func (s *serverAPI) GetFollowing(
+	ctx context.Context,
+	req *authv1.GetFollowingRequest,
+) (*authv1.GetFollowingResponse, error) {
+	ids, err := s.FollowService.GetFollowingIds(int(req.GetUserId()))
+	if err != nil {
+		logrus.WithField("op", "grpc_service.GetFollowing").Errorln(err)
+		return &authv1.GetFollowingResponse{
+			StatusCode: 500,
+		}, nil
+	}
+
+	userIds := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		userIds = append(userIds, int64(id))
+	}
+	return &authv1.GetFollowingResponse{
+		StatusCode: 200,
+		UserIds:    userIds,
+	}, nil
+}
+
+func (s *serverAPI) GetUser(
+	ctx context.Context,
+	req *authv1.GetUserRequest,
+) (*authv1.GetUserResponse, error) {
+	profile, err := s.ProfileService.GetProfile(int(req.GetUserId()))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return &authv1.GetUserResponse{
+			StatusCode: 404,
+		}, nil
+	}
+	if err != nil {
+		logrus.WithField("op", "grpc_service.GetUser").Errorln(err)
+		return &authv1.GetUserResponse{
+			StatusCode: 500,
+		}, nil
+	}
+
+	return &authv1.GetUserResponse{
+		StatusCode: 200,
+		User:       toUser(profile),
 	}, nil
 }
+
+func (s *serverAPI) BatchGetUsers(
+	ctx context.Context,
+	req *authv1.BatchGetUsersRequest,
+) (*authv1.BatchGetUsersResponse, error) {
+	if len(req.GetUserIds()) > MaxBatchGetUsers {
+		return &authv1.BatchGetUsersResponse{
+			StatusCode: 400,
+		}, nil
+	}
+	userIds := make([]int, 0, len(req.GetUserIds()))
+	for _, id := range req.GetUserIds() {
+		userIds = append(userIds, int(id))
+	}
+	profiles, err := s.ProfileService.GetProfiles(userIds)
+	if err != nil {
+		logrus.WithField("op", "grpc_service.BatchGetUsers").Errorln(err)
+		return &authv1.BatchGetUsersResponse{
+			StatusCode: 500,
+		}, nil
+	}
+
+	users := make([]*authv1.User, 0, len(profiles))
+	for i := range profiles {
+		users = append(users, toUser(&profiles[i]))
+	}
+	return &authv1.BatchGetUsersResponse{
+		StatusCode: 200,
+		Users:      users,
+	}, nil
+}
+
+func (s *serverAPI) ResolveUsername(
+	ctx context.Context,
+	req *authv1.ResolveUsernameRequest,
+) (*authv1.ResolveUsernameResponse, error) {
+	profile, err := s.ProfileService.GetProfileByUsername(req.GetUsername())
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return &authv1.ResolveUsernameResponse{
+			StatusCode: 404,
+		}, nil
+	}
+	if err != nil {
+		logrus.WithField("op", "grpc_service.ResolveUsername").Errorln(err)
+		return &authv1.ResolveUsernameResponse{
+			StatusCode: 500,
+		}, nil
+	}
+
+	return &authv1.ResolveUsernameResponse{
+		StatusCode: 200,
+		UserId:     int64(profile.Id),
+	}, nil
+}
+
+func (s *serverAPI) CheckUserExists(
+	ctx context.Context,
+	req *authv1.CheckUserExistsRequest,
+) (*authv1.CheckUserExistsResponse, error) {
+	_, err := s.ProfileService.GetProfile(int(req.GetUserId()))
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		logrus.WithField("op", "grpc_service.CheckUserExists").Errorln(err)
+		return &authv1.CheckUserExistsResponse{
+			StatusCode: 500,
+		}, nil
+	}
+
+	return &authv1.CheckUserExistsResponse{
+		StatusCode: 200,
+		Exists:     err == nil,
+	}, nil
+}
+
+func toUser(profile *models.Profile) *authv1.User {
+	return &authv1.User{
+		Id:            int64(profile.Id),
+		Username:      profile.Username,
+		DisplayName:   profile.DisplayName,
+		AvatarVersion: int64(profile.AvatarVersion),
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"user_service/internal/grpc_service"
+	"user_service/internal/services"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
@@ -14,10 +15,15 @@ type GrpcHandler struct {
 	port       int
 }
 
-func NewGrpcHandler(port int, jwtSecretKey string) *GrpcHandler {
+func NewGrpcHandler(
+	port int,
+	userService services.UserServiceIn,
+	followService services.FollowServiceIn,
+	profileService services.ProfileServiceIn,
+) *GrpcHandler {
 	gRPCServer := grpc.NewServer()
 
-	grpc_service.Register(gRPCServer, jwtSecretKey)
+	grpc_service.Register(gRPCServer, userService, followService, profileService)
 
 	return &GrpcHandler{
 		gRPCServer: gRPCServer,
@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"user_service/internal/keys"
 	"user_service/internal/metrics"
 	"user_service/internal/models"
+	"user_service/internal/repository"
 	"user_service/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -16,23 +23,58 @@ type HandlerInterface interface {
 	New() http.Handler
 	Register(c *gin.Context)
 	SignIn(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
 	UpdateAvatar(c *gin.Context)
+	GetAvatar(c *gin.Context)
+	Follow(c *gin.Context)
+	Unfollow(c *gin.Context)
+	GetFollowers(c *gin.Context)
+	GetFollowing(c *gin.Context)
+	GetJWKS(c *gin.Context)
+	GetProfile(c *gin.Context)
+	UpdateProfile(c *gin.Context)
+	SearchUsers(c *gin.Context)
 }
 
 type HttpHandler struct {
-	UserService services.UserService
+	UserService    services.UserService
+	FollowService  services.FollowService
+	ProfileService services.ProfileService
+	Keys           *keys.KeySet
 }
 
 var _ HandlerInterface = &HttpHandler{}
 
+const maxAvatarUploadSize = 5 << 20
+
+// avatarTypes are the image formats photo_service can decode
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
 func (h *HttpHandler) New() http.Handler {
 	router := gin.Default()
+	router.GET("/.well-known/jwks.json", h.GetJWKS)
 
 	userApi := router.Group("/userApi")
 	{
 		userApi.POST("/register", h.Register)
 		userApi.POST("/signin", h.SignIn)
+		userApi.POST("/refresh", h.Refresh)
+		userApi.POST("/logout", h.Logout)
 		userApi.PUT("/updateAvatar", h.UpdateAvatar)
+		userApi.GET("/users/:id/avatar", h.GetAvatar)
+		userApi.POST("/follow", h.Follow)
+		userApi.DELETE("/unfollow", h.Unfollow)
+		userApi.GET("/followers", h.GetFollowers)
+		userApi.GET("/following", h.GetFollowing)
+		userApi.GET("/profile", h.GetProfile)
+		userApi.PUT("/profile", h.UpdateProfile)
+		userApi.GET("/searchUsers", h.SearchUsers)
 	}
 
 	return router.Handler()
@@ -51,13 +93,13 @@ func (h *HttpHandler) Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
 		return
 	}
-	token, err := h.UserService.Register(user.Username, user.Password)
+	tokens, err := h.UserService.Register(user.Username, user.Password)
 	if err != nil {
 		logrus.Errorln(err)
 		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, models.TokenResponse{Token: token})
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (h *HttpHandler) SignIn(c *gin.Context) {
@@ -74,37 +116,65 @@ func (h *HttpHandler) SignIn(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
 		return
 	}
-	token, err := h.UserService.SignIn(user.Username, user.Password)
+	tokens, err := h.UserService.SignIn(user.Username, user.Password)
 	if err != nil {
 		logrus.Errorln(err)
 		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, models.TokenResponse{Token: token})
+	c.JSON(http.StatusOK, tokens)
 }
 
-func (h *HttpHandler) UpdateAvatar(c *gin.Context) {
+func (h *HttpHandler) Refresh(c *gin.Context) {
 	startTime := time.Now()
 	defer func() {
 		metrics.Observe(time.Since(startTime), c.Writer.Status())
 	}()
 
-	// Getting userId
-	authHeader := c.Request.Header.Get("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, models.AppError{Message: "Unauthorized"})
+	var request models.RefreshRequest
+	err := c.BindJSON(&request)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
 		return
 	}
-	headerArr := strings.Split(authHeader, " ")
-	if len(headerArr) != 2 {
-		c.JSON(http.StatusUnauthorized, models.AppError{Message: "Unauthorized"})
+	tokens, err := h.UserService.Refresh(request.RefreshToken)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusUnauthorized, models.AppError{Message: err.Error()})
 		return
 	}
-	token := headerArr[1]
-	userId, _, err := h.UserService.GetDataFromToken(token)
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *HttpHandler) Logout(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	token, err := h.getBearerToken(c)
+	if err != nil {
+		return
+	}
+	err = h.UserService.Logout(token)
 	if err != nil {
 		logrus.Errorln(err)
-		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		c.JSON(http.StatusUnauthorized, models.AppError{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.AppError{Message: "OK"})
+}
+
+func (h *HttpHandler) UpdateAvatar(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	// Getting userId
+	userId, err := h.getUserIdFromToken(c)
+	if err != nil {
 		return
 	}
 
@@ -116,21 +186,17 @@ func (h *HttpHandler) UpdateAvatar(c *gin.Context) {
 		return
 	}
 	defer userFile.Close()
-	buff512 := make([]byte, 512)
-	if _, err = userFile.Read(buff512); err != nil {
+	fileBytes, err := io.ReadAll(io.LimitReader(userFile, maxAvatarUploadSize+1))
+	if err != nil {
 		logrus.Errorln(err)
 		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
 		return
 	}
-	mimeType := http.DetectContentType(buff512)
-	if mimeType != "image/png" {
-		logrus.Errorln(err)
-		c.JSON(http.StatusBadRequest, models.AppError{Message: "Invalid file type"})
+	if len(fileBytes) > maxAvatarUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.AppError{Message: "file is too large"})
 		return
 	}
-	fileBytes := []byte{}
-	if _, err = userFile.Read(fileBytes); err != nil {
-		logrus.Errorln(err)
+	if !avatarTypes[http.DetectContentType(fileBytes)] {
 		c.JSON(http.StatusBadRequest, models.AppError{Message: "Invalid file type"})
 		return
 	}
@@ -141,5 +207,347 @@ func (h *HttpHandler) UpdateAvatar(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
 		return
 	}
+	c.JSON(http.StatusAccepted, models.AppError{Message: "OK"})
+}
+
+// GetAvatar serves the avatar image of a user in the size given by the size query
+// parameter. Clients that request it with the current version in the v query
+// parameter, as post responses do, may cache it forever.
+func (h *HttpHandler) GetAvatar(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.AppError{Message: "invalid user id"})
+		return
+	}
+	size := services.DefaultAvatarSize
+	if c.Query("size") != "" {
+		size, err = strconv.Atoi(c.Query("size"))
+		if err != nil || !slices.Contains(services.AvatarSizes, size) {
+			c.JSON(http.StatusBadRequest, models.AppError{Message: "invalid avatar size"})
+			return
+		}
+	}
+	avatar, err := h.UserService.GetAvatar(userId, size)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusNotFound, models.AppError{Message: "avatar not found"})
+		return
+	}
+
+	etag := fmt.Sprintf(`"%d-%d-%d"`, userId, avatar.Version, size)
+	c.Header("ETag", etag)
+	if c.Query("v") == strconv.Itoa(avatar.Version) {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "public, max-age=300")
+	}
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, http.DetectContentType(avatar.Data), avatar.Data)
+}
+
+func (h *HttpHandler) Follow(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserIdFromToken(c)
+	if err != nil {
+		return
+	}
+	var request models.FollowRequest
+	err = c.BindJSON(&request)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
+	err = h.FollowService.Follow(userId, request.UserId)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, models.AppError{Message: "OK"})
 }
+
+func (h *HttpHandler) Unfollow(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserIdFromToken(c)
+	if err != nil {
+		return
+	}
+	var request models.FollowRequest
+	err = c.BindJSON(&request)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
+	err = h.FollowService.Unfollow(userId, request.UserId)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, models.AppError{Message: "OK"})
+}
+
+func (h *HttpHandler) GetFollowers(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	userId, cursor, limit, err := h.getUserListParams(c)
+	if err != nil {
+		return
+	}
+	options, err := h.getUserListOptions(c)
+	if err != nil {
+		return
+	}
+	users, nextCursor, err := h.FollowService.GetFollowers(userId, cursor, limit, options)
+	if errors.Is(err, services.ErrInvalidListOptions) {
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusInternalServerError, models.AppError{Message: "Internal Server Error"})
+		return
+	}
+	c.JSON(http.StatusOK, models.UserListResponse{Users: users, NextCursor: nextCursor})
+}
+
+func (h *HttpHandler) GetFollowing(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	userId, cursor, limit, err := h.getUserListParams(c)
+	if err != nil {
+		return
+	}
+	options, err := h.getUserListOptions(c)
+	if err != nil {
+		return
+	}
+	users, nextCursor, err := h.FollowService.GetFollowing(userId, cursor, limit, options)
+	if errors.Is(err, services.ErrInvalidListOptions) {
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusInternalServerError, models.AppError{Message: "Internal Server Error"})
+		return
+	}
+	c.JSON(http.StatusOK, models.UserListResponse{Users: users, NextCursor: nextCursor})
+}
+
+// GetJWKS publishes the public keys used to sign access tokens,
+// so other services can verify tokens without calling user_service
+func (h *HttpHandler) GetJWKS(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.Keys.JWKS())
+}
+
+// GetProfile returns the public profile of the user given by the userId or username query parameter
+func (h *HttpHandler) GetProfile(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	var profile *models.Profile
+	var err error
+	if username := c.Query("username"); username != "" {
+		profile, err = h.ProfileService.GetProfileByUsername(username)
+	} else {
+		userId, convErr := strconv.Atoi(c.Query("userId"))
+		if convErr != nil {
+			c.JSON(http.StatusBadRequest, models.AppError{Message: "userId or username is required"})
+			return
+		}
+		profile, err = h.ProfileService.GetProfile(userId)
+	}
+	if errors.Is(err, repository.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, models.AppError{Message: "user not found"})
+		return
+	}
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusInternalServerError, models.AppError{Message: "Internal Server Error"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *HttpHandler) UpdateProfile(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	userId, err := h.getUserIdFromToken(c)
+	if err != nil {
+		return
+	}
+	var request models.UpdateProfileRequest
+	err = c.BindJSON(&request)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
+	profile, err := h.ProfileService.UpdateProfile(userId, request)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// SearchUsers finds users by the beginning of their username or display name,
+// names that are only similar to the query come after the prefix matches. The
+// results are ranked, so they are paged by offset rather than by cursor.
+func (h *HttpHandler) SearchUsers(c *gin.Context) {
+	startTime := time.Now()
+	defer func() {
+		metrics.Observe(time.Since(startTime), c.Writer.Status())
+	}()
+
+	offset, err := h.getQueryInt(c, "offset")
+	if err != nil {
+		return
+	}
+	limit, err := h.getQueryInt(c, "limit")
+	if err != nil {
+		return
+	}
+	if strings.TrimSpace(c.Query("q")) == "" {
+		c.JSON(http.StatusBadRequest, models.AppError{Message: "q is required"})
+		return
+	}
+	profiles, nextOffset, err := h.ProfileService.SearchProfiles(c.Query("q"), offset, limit)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusInternalServerError, models.AppError{Message: "Internal Server Error"})
+		return
+	}
+	c.JSON(http.StatusOK, models.ProfileListResponse{Profiles: profiles, NextOffset: nextOffset})
+}
+
+// getUserIdFromToken reads the bearer token from the Authorization header,
+// on failure the error response is already written
+func (h *HttpHandler) getUserIdFromToken(c *gin.Context) (int, error) {
+	token, err := h.getBearerToken(c)
+	if err != nil {
+		return 0, err
+	}
+	userId, _, err := h.UserService.GetDataFromToken(token)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: err.Error()})
+		return 0, err
+	}
+	return userId, nil
+}
+
+// getBearerToken extracts the token from the Authorization header,
+// on failure the error response is already written
+func (h *HttpHandler) getBearerToken(c *gin.Context) (string, error) {
+	authHeader := c.Request.Header.Get("Authorization")
+	headerArr := strings.Split(authHeader, " ")
+	if authHeader == "" || len(headerArr) != 2 {
+		c.JSON(http.StatusUnauthorized, models.AppError{Message: "Unauthorized"})
+		return "", errors.New("unauthorized")
+	}
+	return headerArr[1], nil
+}
+
+// getUserListOptions parses the sort, from and to query parameters of the user list endpoints,
+// sort is newest or oldest and from and to are RFC 3339 times
+func (h *HttpHandler) getUserListOptions(c *gin.Context) (models.UserListOptions, error) {
+	from, err := h.getQueryTime(c, "from")
+	if err != nil {
+		return models.UserListOptions{}, err
+	}
+	to, err := h.getQueryTime(c, "to")
+	if err != nil {
+		return models.UserListOptions{}, err
+	}
+	return models.UserListOptions{Sort: c.Query("sort"), From: from, To: to}, nil
+}
+
+// getQueryTime parses an optional RFC 3339 query parameter, an absent parameter yields
+// the zero time. On failure the error response is already written.
+func (h *HttpHandler) getQueryTime(c *gin.Context, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: "invalid " + key})
+		return time.Time{}, err
+	}
+	return parsed, nil
+}
+
+// getUserListParams parses the userId, cursor and limit query parameters of the list endpoints,
+// on failure the error response is already written
+func (h *HttpHandler) getUserListParams(c *gin.Context) (int, int, int, error) {
+	userId, err := strconv.Atoi(c.Query("userId"))
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: "invalid userId"})
+		return 0, 0, 0, err
+	}
+	cursor, err := h.getQueryInt(c, "cursor")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	limit, err := h.getQueryInt(c, "limit")
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return userId, cursor, limit, nil
+}
+
+// getQueryInt parses an optional integer query parameter, an absent parameter yields 0.
+// On failure the error response is already written.
+func (h *HttpHandler) getQueryInt(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logrus.Errorln(err)
+		c.JSON(http.StatusBadRequest, models.AppError{Message: "invalid " + key})
+		return 0, err
+	}
+	return parsed, nil
+}
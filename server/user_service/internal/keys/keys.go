@@ -0,0 +1,239 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a single token signing key, Id is sent in the kid header of the tokens it signs
+type Key struct {
+	Id      string
+	Method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// KeySet signs tokens with its active key and verifies tokens signed by any of its keys,
+// so tokens issued before a rotation stay valid until they expire
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+// NewHMAC returns a key set that signs and verifies with a shared HS256 secret
+func NewHMAC(secret string) (*KeySet, error) {
+	const op = "keys.NewHMAC"
+
+	if secret == "" {
+		return nil, fmt.Errorf("%s: empty secret", op)
+	}
+	key := &Key{Id: "", Method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+	return &KeySet{active: key, keys: map[string]*Key{key.Id: key}}, nil
+}
+
+// LoadDir loads every *.pem private key (PKCS#8 or PKCS#1) from dir. Keys are
+// sorted by file name and the last one becomes the active signing key, so a key
+// is rotated by adding a file that sorts after the current one and restarting.
+func LoadDir(dir string) (*KeySet, error) {
+	const op = "keys.LoadDir"
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s: no *.pem keys found in %s", op, dir)
+	}
+	sort.Strings(paths)
+
+	ks := &KeySet{keys: map[string]*Key{}}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		private, err := parsePrivateKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+		}
+		key, err := newKey(private)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, path, err)
+		}
+		ks.keys[key.Id] = key
+		ks.active = key
+	}
+	return ks, nil
+}
+
+// Generate creates a key set with a single random key for the given algorithm.
+// The key only lives in memory, tokens signed with it become invalid on restart.
+func Generate(alg string) (*KeySet, error) {
+	const op = "keys.Generate"
+
+	private, err := generatePrivateKey(alg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	key, err := newKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &KeySet{active: key, keys: map[string]*Key{key.Id: key}}, nil
+}
+
+// GenerateFile writes a new random key for the given algorithm to dir as a PKCS#8 PEM
+// file named after the current time, so it sorts after the existing keys and becomes
+// the active one the next time the directory is loaded. It returns the path of the file.
+func GenerateFile(dir string, alg string) (string, error) {
+	const op = "keys.GenerateFile"
+
+	private, err := generatePrivateKey(alg)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	path := filepath.Join(dir, time.Now().UTC().Format("20060102T150405Z")+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer file.Close()
+	if err = pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	return path, nil
+}
+
+// HasKeys reports whether dir holds any *.pem key
+func HasKeys(dir string) (bool, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	return len(paths) > 0, err
+}
+
+func generatePrivateKey(alg string) (interface{}, error) {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	if ks.active.Id != "" {
+		token.Header["kid"] = ks.active.Id
+	}
+	return token.SignedString(ks.active.private)
+}
+
+// Keyfunc picks the verification key by the kid header of the token
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+	}
+	return key.public, nil
+}
+
+// Methods lists the algorithms of all keys in the set, to be used with jwt.WithValidMethods
+func (ks *KeySet) Methods() []string {
+	seen := map[string]bool{}
+	methods := []string{}
+	for _, key := range ks.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
+
+func newKey(private interface{}) (*Key, error) {
+	key := &Key{private: private}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+		key.public = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+		key.public = k.Public()
+	default:
+		return nil, errors.New("unsupported key type, expected RSA or Ed25519")
+	}
+	jwk, err := toJWK(key)
+	if err != nil {
+		return nil, err
+	}
+	key.Id = thumbprint(jwk)
+	return key, nil
+}
+
+func parsePrivateKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// thumbprint computes the RFC 7638 thumbprint of a public key, which is used as its kid
+func thumbprint(jwk *JWK) string {
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"OKP","x":"%s"}`, jwk.Crv, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func toJWK(key *Key) (*JWK, error) {
+	jwk := &JWK{Kid: key.Id, Use: "sig", Alg: key.Method.Alg()}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+	return jwk, nil
+}
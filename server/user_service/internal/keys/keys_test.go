@@ -0,0 +1,42 @@
+package keys
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGenerateFileRoundTrip(t *testing.T) {
+	for _, alg := range []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()} {
+		t.Run(alg, func(t *testing.T) {
+			dir := t.TempDir()
+			if exists, err := HasKeys(dir); err != nil || exists {
+				t.Fatalf("HasKeys of an empty dir = %v, %v", exists, err)
+			}
+			if _, err := GenerateFile(dir, alg); err != nil {
+				t.Fatal(err)
+			}
+			ks, err := LoadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := ks.Sign(jwt.MapClaims{"sub": "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := jwt.Parse(token, ks.Keyfunc, jwt.WithValidMethods(ks.Methods()))
+			if err != nil || !parsed.Valid {
+				t.Fatalf("token signed with the loaded key doesn't verify: %v", err)
+			}
+			if parsed.Header["kid"] != ks.active.Id {
+				t.Errorf("kid = %v, want %s", parsed.Header["kid"], ks.active.Id)
+			}
+		})
+	}
+}
+
+func TestGenerateFileRejectsUnknownAlgorithm(t *testing.T) {
+	if _, err := GenerateFile(t.TempDir(), "HS256"); err == nil {
+		t.Error("GenerateFile accepted HS256")
+	}
+}
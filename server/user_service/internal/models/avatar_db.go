@@ -0,0 +1,6 @@
+package models
+
+type AvatarDb struct {
+	Data    []byte
+	Version int
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+const UserFollowed = "user.followed"
+
+// FollowEvent is published to the user_events exchange when a user starts following another one
+type FollowEvent struct {
+	Type       string
+	FollowerId int
+	FolloweeId int
+	OccurredAt time.Time
+}
+
+func (e FollowEvent) EventType() string { return e.Type }
+func (FollowEvent) EventVersion() int   { return 1 }
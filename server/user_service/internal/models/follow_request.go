@@ -0,0 +1,5 @@
+package models
+
+type FollowRequest struct {
+	UserId int `json:"user_id"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Profile is the public profile of a user. Legacy users signed up before
+// user_service kept track of sign up times, their CreatedAt is when it started to.
+type Profile struct {
+	Id            int       `json:"id"`
+	Username      string    `json:"username"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
+	Location      string    `json:"location"`
+	Website       string    `json:"website"`
+	AvatarVersion int       `json:"avatar_version"`
+	Legacy        bool      `json:"legacy"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
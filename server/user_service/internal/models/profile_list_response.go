@@ -0,0 +1,6 @@
+package models
+
+type ProfileListResponse struct {
+	Profiles   []Profile `json:"profiles"`
+	NextOffset int       `json:"next_offset"`
+}
@@ -0,0 +1,5 @@
+package models
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
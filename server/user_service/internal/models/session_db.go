@@ -1,6 +1,7 @@
 package models
 
-type CreatePhotoMessage struct {
+type SessionDb struct {
+	Id       string
 	UserId   int
 	Username string
 }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+)
+
+// UserEvent is published to the user_events exchange whenever public user data
+// changes, it carries a full snapshot so consumers can keep their own copy
+type UserEvent struct {
+	Type          string
+	UserId        int
+	Username      string
+	DisplayName   string
+	AvatarVersion int
+	OccurredAt    time.Time
+}
+
+func (e UserEvent) EventType() string { return e.Type }
+func (UserEvent) EventVersion() int   { return 1 }
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type UserInfo struct {
+	Id        int       `json:"id"`
+	Username  string    `json:"username"`
+	Legacy    bool      `json:"legacy"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	SortNewest = "newest"
+	SortOldest = "oldest"
+)
+
+// UserListOptions orders and filters a list of users by when they signed up.
+// Sort is SortNewest or SortOldest, an empty Sort lists the newest first like the
+// post lists do. From and To bound the sign up time, To is exclusive and zero times
+// don't filter. Legacy users, whose sign up time is unknown, are left out as soon
+// as From or To is set.
+type UserListOptions struct {
+	Sort string
+	From time.Time
+	To   time.Time
+}
@@ -0,0 +1,6 @@
+package models
+
+type UserListResponse struct {
+	Users      []UserInfo `json:"users"`
+	NextCursor int        `json:"next_cursor"`
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"shared/outbox"
+	"time"
+	"user_service/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type FollowRepositoryIn interface {
+	Follow(followerId, followeeId int, messages []outbox.Message) error
+	Unfollow(followerId, followeeId int) error
+	GetFollowers(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, error)
+	GetFollowing(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, error)
+	GetFollowingIds(userId int) ([]int, error)
+}
+
+type FollowRepository struct {
+	Db *sqlx.DB
+}
+
+var _ FollowRepositoryIn = &FollowRepository{}
+
+// Follow is idempotent, the outbox messages announcing the follow are saved
+// in the same transaction only when the user wasn't followed yet
+func (fr *FollowRepository) Follow(followerId, followeeId int, messages []outbox.Message) error {
+	const op = "repository.Follow"
+
+	tx, err := fr.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO user_follow (follower_id, followee_id) VALUES ($1, $2)
+	 ON CONFLICT DO NOTHING`, followerId, followeeId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if inserted == 0 {
+		return nil
+	}
+	if err = outbox.Add(tx, messages); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (fr *FollowRepository) Unfollow(followerId, followeeId int) error {
+	const op = "repository.Unfollow"
+
+	_, err := fr.Db.Exec("DELETE FROM user_follow WHERE follower_id = $1 AND followee_id = $2",
+		followerId, followeeId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// GetFollowers pages through the users following userId ordered by user id,
+// a zero cursor starts from the beginning
+func (fr *FollowRepository) GetFollowers(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, error) {
+	const op = "repository.GetFollowers"
+
+	conditions, orderBy := userListClauses(options)
+	users, err := fr.queryUsers(`SELECT au.id, au.username, au.legacy, au.created_at FROM user_follow AS uf
+	 INNER JOIN app_user AS au ON uf.follower_id = au.id
+	 WHERE uf.followee_id = $1 AND `+conditions+` `+orderBy+` LIMIT $5`,
+		userId, cursor, nullTime(options.From), nullTime(options.To), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+// GetFollowing pages through the users followed by userId ordered by user id,
+// a zero cursor starts from the beginning
+func (fr *FollowRepository) GetFollowing(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, error) {
+	const op = "repository.GetFollowing"
+
+	conditions, orderBy := userListClauses(options)
+	users, err := fr.queryUsers(`SELECT au.id, au.username, au.legacy, au.created_at FROM user_follow AS uf
+	 INNER JOIN app_user AS au ON uf.followee_id = au.id
+	 WHERE uf.follower_id = $1 AND `+conditions+` `+orderBy+` LIMIT $5`,
+		userId, cursor, nullTime(options.From), nullTime(options.To), limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+func (fr *FollowRepository) GetFollowingIds(userId int) ([]int, error) {
+	const op = "repository.GetFollowingIds"
+
+	ids := []int{}
+	err := fr.Db.Select(&ids, "SELECT followee_id FROM user_follow WHERE follower_id = $1", userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return ids, nil
+}
+
+func (fr *FollowRepository) queryUsers(query string, args ...any) ([]models.UserInfo, error) {
+	rows, err := fr.Db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserInfo{}
+	for rows.Next() {
+		var user models.UserInfo
+		if err = rows.Scan(&user.Id, &user.Username, &user.Legacy, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// userListClauses returns the conditions that page a list of users (app_user aliased
+// as au) from the cursor $2 and bound their sign up time by $3 and $4, together with
+// the ORDER BY they need. User ids are handed out in sign up order.
+func userListClauses(options models.UserListOptions) (string, string) {
+	comparison, direction := "<", "DESC"
+	if options.Sort == models.SortOldest {
+		comparison, direction = ">", "ASC"
+	}
+	conditions := `($2 = 0 OR au.id ` + comparison + ` $2)
+	 AND ($3::timestamptz IS NULL OR (au.created_at >= $3 AND NOT au.legacy))
+	 AND ($4::timestamptz IS NULL OR (au.created_at < $4 AND NOT au.legacy))`
+	return conditions, "ORDER BY au.id " + direction
+}
+
+// nullTime turns the zero time into NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user_service/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, session revoked")
+)
+
+type SessionRepositoryIn interface {
+	CreateSession(sessionId string, userId int, refreshTokenHash string, expiresAt time.Time) error
+	RotateRefreshToken(oldTokenHash, newTokenHash string, expiresAt time.Time) (*models.SessionDb, error)
+	RevokeSession(sessionId string) error
+	IsSessionActive(sessionId string) (bool, error)
+}
+
+type SessionRepository struct {
+	Db *sqlx.DB
+}
+
+var _ SessionRepositoryIn = &SessionRepository{}
+
+func (sr *SessionRepository) CreateSession(sessionId string, userId int, refreshTokenHash string, expiresAt time.Time) error {
+	const op = "repository.CreateSession"
+
+	tx, err := sr.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO user_session (id, user_id) VALUES ($1, $2)", sessionId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = tx.Exec("INSERT INTO refresh_token (token_hash, session_id, expires_at) VALUES ($1, $2, $3)",
+		refreshTokenHash, sessionId, expiresAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RotateRefreshToken marks the old refresh token as used and stores its replacement.
+// Presenting a token that was already rotated means it leaked, so the whole session
+// is revoked and ErrRefreshTokenReused is returned.
+func (sr *SessionRepository) RotateRefreshToken(oldTokenHash, newTokenHash string, expiresAt time.Time) (*models.SessionDb, error) {
+	const op = "repository.RotateRefreshToken"
+
+	tx, err := sr.Db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var (
+		session        models.SessionDb
+		tokenExpiresAt time.Time
+		usedAt         sql.NullTime
+		revokedAt      sql.NullTime
+	)
+	err = tx.QueryRow(`SELECT us.id, us.user_id, au.username, rt.expires_at, rt.used_at, us.revoked_at
+	 FROM refresh_token AS rt
+	 INNER JOIN user_session AS us ON rt.session_id = us.id
+	 INNER JOIN app_user AS au ON us.user_id = au.id
+	 WHERE rt.token_hash = $1 FOR UPDATE OF rt, us`, oldTokenHash).
+		Scan(&session.Id, &session.UserId, &session.Username, &tokenExpiresAt, &usedAt, &revokedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if revokedAt.Valid || tokenExpiresAt.Before(time.Now()) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if usedAt.Valid {
+		_, err = tx.Exec("UPDATE user_session SET revoked_at = now() WHERE id = $1", session.Id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	_, err = tx.Exec("UPDATE refresh_token SET used_at = now() WHERE token_hash = $1", oldTokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	_, err = tx.Exec("INSERT INTO refresh_token (token_hash, session_id, expires_at) VALUES ($1, $2, $3)",
+		newTokenHash, session.Id, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &session, nil
+}
+
+func (sr *SessionRepository) RevokeSession(sessionId string) error {
+	const op = "repository.RevokeSession"
+
+	_, err := sr.Db.Exec("UPDATE user_session SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL",
+		sessionId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (sr *SessionRepository) IsSessionActive(sessionId string) (bool, error) {
+	const op = "repository.IsSessionActive"
+
+	var active bool
+	err := sr.Db.QueryRow("SELECT revoked_at IS NULL FROM user_session WHERE id = $1", sessionId).Scan(&active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return active, nil
+}
@@ -1,37 +1,75 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"shared/outbox"
+	"strings"
 	"user_service/internal/models"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type UserRepositoryIn interface {
-	CreateUser(username, password string) (int, error)
+	CreateUser(username, password string, messages func(userId int) ([]outbox.Message, error)) (int, error)
 	FindUserByUsername(username string) *models.UserDb
-	UpdateAvatar(newAvatar []byte, userId int) error
+	FindUserById(userId int) *models.UserDb
+	GetAvatar(userId, size int) (*models.AvatarDb, error)
+	SaveAvatars(userId int, avatars map[int][]byte, messages func(profile *models.Profile) ([]outbox.Message, error)) error
+	GetProfile(userId int) (*models.Profile, error)
+	GetProfileByUsername(username string) (*models.Profile, error)
+	GetProfilesByIds(userIds []int) ([]models.Profile, error)
+	SearchProfiles(query string, offset, limit int) ([]models.Profile, error)
+	UpdateProfile(
+		userId int,
+		profile models.UpdateProfileRequest,
+		messages func(profile *models.Profile) ([]outbox.Message, error),
+	) error
 }
 
+var ErrUserNotFound = errors.New("user not found")
+
+const profileColumns = `id, username, display_name, bio, location, website, avatar_version, legacy, created_at,
+	updated_at`
+
 type UserRepository struct {
 	Db *sqlx.DB
 }
 
 var _ UserRepositoryIn = &UserRepository{}
 
-func (ur *UserRepository) CreateUser(username, password string) (int, error) {
+// CreateUser inserts a user and, in the same transaction, the outbox messages
+// that announce it. messages is called with the id of the new user.
+func (ur *UserRepository) CreateUser(
+	username, password string,
+	messages func(userId int) ([]outbox.Message, error),
+) (int, error) {
 	const op = "repository.CreateUser"
 
+	tx, err := ur.Db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
 	var userId int
-	stmt, err := ur.Db.Prepare(`INSERT INTO app_user (username, password, avatar) VALUES ($1, $2, $3) RETURNING id`)
+	err = tx.QueryRow(`INSERT INTO app_user (username, password, avatar) VALUES ($1, $2, $3) RETURNING id`,
+		username, password, nil).Scan(&userId)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	err = stmt.QueryRow(username, password, nil).Scan(&userId)
+	outboxMessages, err := messages(userId)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	stmt.Close()
+	if err = outbox.Add(tx, outboxMessages); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 	return userId, nil
 }
 
@@ -49,10 +87,208 @@ func (ur *UserRepository) FindUserByUsername(username string) *models.UserDb {
 	return &candidate
 }
 
-func (ur *UserRepository) UpdateAvatar(newAvatar []byte, userId int) error {
-	_, err := ur.Db.Exec("UPDATE app_user SET avatar = $1 WHERE id = $2", newAvatar, userId)
+func (ur *UserRepository) FindUserById(userId int) *models.UserDb {
+	var candidate models.UserDb
+	err := ur.Db.QueryRow("SELECT id, username, password FROM app_user WHERE id=$1", userId).
+		Scan(&candidate.Id, &candidate.Username, &candidate.Password)
 	if err != nil {
-		return err
+		return nil
+	}
+	return &candidate
+}
+
+// GetAvatar returns the avatar of a user in the given size. Users whose avatar
+// was never rendered in sizes get the legacy 200px image.
+func (ur *UserRepository) GetAvatar(userId, size int) (*models.AvatarDb, error) {
+	const op = "repository.GetAvatar"
+
+	var avatar models.AvatarDb
+	err := ur.Db.QueryRow(`SELECT COALESCE(ua.data, au.avatar), au.avatar_version FROM app_user au
+	 LEFT JOIN user_avatar ua ON ua.user_id = au.id AND ua.size = $2
+	 WHERE au.id = $1 AND COALESCE(ua.data, au.avatar) IS NOT NULL`, userId, size).
+		Scan(&avatar.Data, &avatar.Version)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return &avatar, nil
+}
+
+// SaveAvatars stores every rendered size of an avatar and bumps the avatar version,
+// the 200px one is also kept in app_user for avatars that predate sizes. The outbox
+// messages that announce it are saved in the same transaction, messages is called
+// with the updated profile.
+func (ur *UserRepository) SaveAvatars(
+	userId int,
+	avatars map[int][]byte,
+	messages func(profile *models.Profile) ([]outbox.Message, error),
+) error {
+	const op = "repository.SaveAvatars"
+
+	tx, err := ur.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for size, data := range avatars {
+		_, err = tx.Exec(`INSERT INTO user_avatar (user_id, size, data) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id, size) DO UPDATE SET data = EXCLUDED.data`, userId, size, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	_, err = tx.Exec("UPDATE app_user SET avatar = $1, avatar_version = avatar_version + 1, updated_at = now() WHERE id = $2",
+		avatars[200], userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = addProfileMessages(tx, userId, messages); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 	return nil
 }
+
+func (ur *UserRepository) GetProfile(userId int) (*models.Profile, error) {
+	const op = "repository.GetProfile"
+
+	profile, err := scanProfile(ur.Db.QueryRow("SELECT "+profileColumns+" FROM app_user WHERE id = $1", userId))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return profile, nil
+}
+
+func (ur *UserRepository) GetProfileByUsername(username string) (*models.Profile, error) {
+	const op = "repository.GetProfileByUsername"
+
+	profile, err := scanProfile(ur.Db.QueryRow("SELECT "+profileColumns+" FROM app_user WHERE username = $1", username))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return profile, nil
+}
+
+// GetProfilesByIds returns the profiles of the given users, ids that don't exist are skipped
+func (ur *UserRepository) GetProfilesByIds(userIds []int) ([]models.Profile, error) {
+	const op = "repository.GetProfilesByIds"
+
+	profiles, err := ur.queryProfiles("SELECT "+profileColumns+" FROM app_user WHERE id = ANY($1)", pq.Array(userIds))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return profiles, nil
+}
+
+// SearchProfiles finds users whose username or display name starts with query
+// or is similar to it. Prefix matches rank first, then the closest names.
+func (ur *UserRepository) SearchProfiles(query string, offset, limit int) ([]models.Profile, error) {
+	const op = "repository.SearchProfiles"
+
+	profiles, err := ur.queryProfiles(`SELECT `+profileColumns+` FROM app_user
+		WHERE lower(username) LIKE $2 ESCAPE '\' OR lower(display_name) LIKE $2 ESCAPE '\'
+			OR lower(username) % $1 OR lower(display_name) % $1
+		ORDER BY (lower(username) LIKE $2 ESCAPE '\' OR lower(display_name) LIKE $2 ESCAPE '\') DESC,
+			greatest(similarity(lower(username), $1), similarity(lower(display_name), $1)) DESC, id
+		OFFSET $3 LIMIT $4`,
+		query, escapeLike(query)+"%", offset, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return profiles, nil
+}
+
+// escapeLike makes the wildcards of a LIKE pattern match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// UpdateProfile replaces the profile fields of a user together with saving the outbox
+// messages that announce it, messages is called with the updated profile
+func (ur *UserRepository) UpdateProfile(
+	userId int,
+	profile models.UpdateProfileRequest,
+	messages func(profile *models.Profile) ([]outbox.Message, error),
+) error {
+	const op = "repository.UpdateProfile"
+
+	tx, err := ur.Db.Begin()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE app_user SET display_name = $1, bio = $2, location = $3, website = $4,
+	 updated_at = now()
+	 WHERE id = $5`, profile.DisplayName, profile.Bio, profile.Location, profile.Website, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%s: %w", op, ErrUserNotFound)
+	}
+	if err = addProfileMessages(tx, userId, messages); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// addProfileMessages saves the outbox messages built from the profile of a user as the
+// transaction sees it, so they carry the changes the transaction made
+func addProfileMessages(tx *sql.Tx, userId int, messages func(profile *models.Profile) ([]outbox.Message, error)) error {
+	profile, err := scanProfile(tx.QueryRow("SELECT "+profileColumns+" FROM app_user WHERE id = $1", userId))
+	if err != nil {
+		return err
+	}
+	outboxMessages, err := messages(profile)
+	if err != nil {
+		return err
+	}
+	return outbox.Add(tx, outboxMessages)
+}
+
+// queryProfiles runs a query that selects profileColumns and scans every row of it
+func (ur *UserRepository) queryProfiles(query string, args ...any) ([]models.Profile, error) {
+	rows, err := ur.Db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []models.Profile{}
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, *profile)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProfile reads a row of profileColumns, a missing row is reported as ErrUserNotFound
+func scanProfile(row rowScanner) (*models.Profile, error) {
+	var profile models.Profile
+	err := row.Scan(&profile.Id, &profile.Username, &profile.DisplayName, &profile.Bio,
+		&profile.Location, &profile.Website, &profile.AvatarVersion, &profile.Legacy,
+		&profile.CreatedAt, &profile.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"shared/outbox"
+	"time"
+	"user_service/internal/amqp"
+	"user_service/internal/models"
+	"user_service/internal/repository"
+)
+
+const (
+	DefaultUserListLimit = 20
+	MaxUserListLimit     = 100
+)
+
+var ErrInvalidListOptions = errors.New("invalid list options")
+
+type FollowServiceIn interface {
+	Follow(followerId, followeeId int) error
+	Unfollow(followerId, followeeId int) error
+	GetFollowers(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, int, error)
+	GetFollowing(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, int, error)
+	GetFollowingIds(userId int) ([]int, error)
+}
+
+type FollowService struct {
+	FollowRepository repository.FollowRepositoryIn
+	UserRepository   repository.UserRepositoryIn
+}
+
+var _ FollowServiceIn = &FollowService{}
+
+func (fs *FollowService) Follow(followerId, followeeId int) error {
+	if followerId == followeeId {
+		return errors.New("you can't follow yourself")
+	}
+	if fs.UserRepository.FindUserById(followeeId) == nil {
+		return errors.New("user with that id doesn't exist")
+	}
+	message, err := newOutboxMessage(amqp.UserEventsExchange, &models.FollowEvent{
+		Type:       models.UserFollowed,
+		FollowerId: followerId,
+		FolloweeId: followeeId,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return fs.FollowRepository.Follow(followerId, followeeId, []outbox.Message{message})
+}
+
+func (fs *FollowService) Unfollow(followerId, followeeId int) error {
+	return fs.FollowRepository.Unfollow(followerId, followeeId)
+}
+
+func (fs *FollowService) GetFollowers(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, int, error) {
+	if err := validateListOptions(options); err != nil {
+		return nil, 0, err
+	}
+	limit = normalizeLimit(limit)
+	users, err := fs.FollowRepository.GetFollowers(userId, cursor, limit+1, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	users, nextCursor := paginateUsers(users, limit)
+	return users, nextCursor, nil
+}
+
+func (fs *FollowService) GetFollowing(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, int, error) {
+	if err := validateListOptions(options); err != nil {
+		return nil, 0, err
+	}
+	limit = normalizeLimit(limit)
+	users, err := fs.FollowRepository.GetFollowing(userId, cursor, limit+1, options)
+	if err != nil {
+		return nil, 0, err
+	}
+	users, nextCursor := paginateUsers(users, limit)
+	return users, nextCursor, nil
+}
+
+func (fs *FollowService) GetFollowingIds(userId int) ([]int, error) {
+	return fs.FollowRepository.GetFollowingIds(userId)
+}
+
+func validateListOptions(options models.UserListOptions) error {
+	if options.Sort != "" && options.Sort != models.SortNewest && options.Sort != models.SortOldest {
+		return fmt.Errorf("%w: sort should be %s or %s", ErrInvalidListOptions, models.SortNewest, models.SortOldest)
+	}
+	if !options.From.IsZero() && !options.To.IsZero() && !options.From.Before(options.To) {
+		return fmt.Errorf("%w: from should be before to", ErrInvalidListOptions)
+	}
+	return nil
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultUserListLimit
+	}
+	if limit > MaxUserListLimit {
+		return MaxUserListLimit
+	}
+	return limit
+}
+
+// paginateUsers trims a result fetched with limit+1 rows and returns the next cursor,
+// which is 0 when there are no more users
+func paginateUsers(users []models.UserInfo, limit int) ([]models.UserInfo, int) {
+	if len(users) <= limit {
+		return users, 0
+	}
+	users = users[:limit]
+	return users, users[limit-1].Id
+}
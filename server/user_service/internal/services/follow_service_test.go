@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+	"user_service/internal/models"
+	"user_service/internal/repository"
+)
+
+type follow struct {
+	followerId, followeeId int
+}
+
+// fakeFollowRepository lists its users the way the queries of FollowRepository do,
+// the methods the tests don't use panic through the nil interface
+type fakeFollowRepository struct {
+	repository.FollowRepositoryIn
+
+	users   []models.UserInfo
+	follows []follow
+	// limits are the limits the users were asked for with
+	limits []int
+}
+
+func (f *fakeFollowRepository) GetFollowers(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, error) {
+	return f.list(func(follow follow) (int, bool) { return follow.followerId, follow.followeeId == userId },
+		cursor, limit, options)
+}
+
+func (f *fakeFollowRepository) GetFollowing(userId, cursor, limit int, options models.UserListOptions) ([]models.UserInfo, error) {
+	return f.list(func(follow follow) (int, bool) { return follow.followeeId, follow.followerId == userId },
+		cursor, limit, options)
+}
+
+// list pages through the users that match picks out of the follows
+func (f *fakeFollowRepository) list(
+	match func(follow follow) (int, bool),
+	cursor, limit int,
+	options models.UserListOptions,
+) ([]models.UserInfo, error) {
+	f.limits = append(f.limits, limit)
+
+	ranged := !options.From.IsZero() || !options.To.IsZero()
+	users := []models.UserInfo{}
+	for _, follow := range f.follows {
+		id, ok := match(follow)
+		if !ok {
+			continue
+		}
+		for _, user := range f.users {
+			switch {
+			case user.Id != id:
+			case ranged && user.Legacy:
+			case !options.From.IsZero() && user.CreatedAt.Before(options.From):
+			case !options.To.IsZero() && !user.CreatedAt.Before(options.To):
+			default:
+				users = append(users, user)
+			}
+		}
+	}
+	oldestFirst := options.Sort == models.SortOldest
+	sort.Slice(users, func(i, j int) bool {
+		if oldestFirst {
+			return users[i].Id < users[j].Id
+		}
+		return users[i].Id > users[j].Id
+	})
+
+	page := []models.UserInfo{}
+	for _, user := range users {
+		past := cursor == 0 || oldestFirst && user.Id > cursor || !oldestFirst && user.Id < cursor
+		if past && len(page) < limit {
+			page = append(page, user)
+		}
+	}
+	return page, nil
+}
+
+// testFollows has users 2 to 6 following user 1 and user 1 following users 2 and 3.
+// User 2 is a legacy user.
+func testFollows() *fakeFollowRepository {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	follows := &fakeFollowRepository{}
+	for id := 1; id <= 6; id++ {
+		follows.users = append(follows.users, models.UserInfo{
+			Id:        id,
+			Legacy:    id <= 2,
+			CreatedAt: start.Add(time.Duration(id) * time.Hour),
+		})
+		if id > 1 {
+			follows.follows = append(follows.follows, follow{followerId: id, followeeId: 1})
+		}
+	}
+	follows.follows = append(follows.follows, follow{1, 2}, follow{1, 3})
+	return follows
+}
+
+// followerIds walks every page of the followers of user 1 and returns their ids in the order they came
+func followerIds(t *testing.T, followService *FollowService, limit int, options models.UserListOptions) []int {
+	t.Helper()
+
+	ids := []int{}
+	cursor := 0
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatalf("GetFollowers() keeps returning a cursor, got %v so far", ids)
+		}
+		users, next, err := followService.GetFollowers(1, cursor, limit, options)
+		if err != nil {
+			t.Fatalf("GetFollowers() error = %v", err)
+		}
+		if len(users) > limit {
+			t.Fatalf("GetFollowers() returned %d users, want at most %d", len(users), limit)
+		}
+		for _, user := range users {
+			ids = append(ids, user.Id)
+		}
+		if next == 0 {
+			return ids
+		}
+		cursor = next
+	}
+}
+
+func TestGetFollowersPages(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		options models.UserListOptions
+		want    []int
+	}{
+		{"newest first by default", models.UserListOptions{}, []int{6, 5, 4, 3, 2}},
+		{"newest first", models.UserListOptions{Sort: models.SortNewest}, []int{6, 5, 4, 3, 2}},
+		{"oldest first", models.UserListOptions{Sort: models.SortOldest}, []int{2, 3, 4, 5, 6}},
+		{
+			name:    "signed up in a range leaves legacy users out",
+			options: models.UserListOptions{From: start, To: start.Add(5 * time.Hour)},
+			want:    []int{4, 3},
+		},
+		{
+			name:    "signed up until leaves legacy users out",
+			options: models.UserListOptions{Sort: models.SortOldest, To: start.Add(6 * time.Hour)},
+			want:    []int{3, 4, 5},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, limit := range []int{1, 2, 3, 10} {
+				followService := &FollowService{FollowRepository: testFollows()}
+				if got := followerIds(t, followService, limit, test.options); !reflect.DeepEqual(got, test.want) {
+					t.Errorf("pages of %d = %v, want %v", limit, got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetFollowing(t *testing.T) {
+	followService := &FollowService{FollowRepository: testFollows()}
+	users, next, err := followService.GetFollowing(1, 0, 1, models.UserListOptions{Sort: models.SortOldest})
+	if err != nil {
+		t.Fatalf("GetFollowing() error = %v", err)
+	}
+	if len(users) != 1 || users[0].Id != 2 || next != 2 {
+		t.Fatalf("GetFollowing() = %v, %d, want user 2 and the cursor 2", users, next)
+	}
+	users, next, err = followService.GetFollowing(1, next, 1, models.UserListOptions{Sort: models.SortOldest})
+	if err != nil {
+		t.Fatalf("GetFollowing() error = %v", err)
+	}
+	if len(users) != 1 || users[0].Id != 3 || next != 0 {
+		t.Fatalf("GetFollowing() = %v, %d, want user 3 and no cursor", users, next)
+	}
+}
+
+func TestGetFollowersLimits(t *testing.T) {
+	tests := []struct {
+		limit int
+		want  int
+	}{
+		{0, DefaultUserListLimit},
+		{-1, DefaultUserListLimit},
+		{5, 5},
+		{MaxUserListLimit + 1, MaxUserListLimit},
+	}
+	for _, test := range tests {
+		follows := &fakeFollowRepository{}
+		followService := &FollowService{FollowRepository: follows}
+		if _, _, err := followService.GetFollowers(1, 0, test.limit, models.UserListOptions{}); err != nil {
+			t.Fatalf("GetFollowers() error = %v", err)
+		}
+		// One more user is fetched to find out whether another page exists
+		if len(follows.limits) != 1 || follows.limits[0] != test.want+1 {
+			t.Errorf("a limit of %d fetched %v users, want %d", test.limit, follows.limits, test.want+1)
+		}
+	}
+}
+
+func TestGetFollowersRejectsInvalidOptions(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		options models.UserListOptions
+	}{
+		{"unknown sort", models.UserListOptions{Sort: "popular"}},
+		{"empty range", models.UserListOptions{From: start, To: start}},
+		{"reversed range", models.UserListOptions{From: start.Add(time.Hour), To: start}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			follows := testFollows()
+			followService := &FollowService{FollowRepository: follows}
+			if _, _, err := followService.GetFollowers(1, 0, 10, test.options); !errors.Is(err, ErrInvalidListOptions) {
+				t.Fatalf("GetFollowers() error = %v, want ErrInvalidListOptions", err)
+			}
+			if len(follows.limits) != 0 {
+				t.Error("GetFollowers() queried the followers of invalid options")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"user_service/internal/models"
+	"user_service/internal/repository"
+)
+
+const (
+	MaxDisplayNameLength = 64
+	MaxBioLength         = 280
+	MaxLocationLength    = 64
+	MaxWebsiteLength     = 255
+	// MaxSearchQueryLength is the number of characters of a search query that are used
+	MaxSearchQueryLength = 64
+)
+
+type ProfileServiceIn interface {
+	GetProfile(userId int) (*models.Profile, error)
+	GetProfileByUsername(username string) (*models.Profile, error)
+	GetProfiles(userIds []int) ([]models.Profile, error)
+	SearchProfiles(query string, offset, limit int) ([]models.Profile, int, error)
+	UpdateProfile(userId int, request models.UpdateProfileRequest) (*models.Profile, error)
+}
+
+type ProfileService struct {
+	UserRepository repository.UserRepositoryIn
+}
+
+var _ ProfileServiceIn = &ProfileService{}
+
+func (ps *ProfileService) GetProfile(userId int) (*models.Profile, error) {
+	return ps.UserRepository.GetProfile(userId)
+}
+
+func (ps *ProfileService) GetProfileByUsername(username string) (*models.Profile, error) {
+	return ps.UserRepository.GetProfileByUsername(username)
+}
+
+func (ps *ProfileService) GetProfiles(userIds []int) ([]models.Profile, error) {
+	if len(userIds) == 0 {
+		return []models.Profile{}, nil
+	}
+	return ps.UserRepository.GetProfilesByIds(userIds)
+}
+
+// SearchProfiles looks users up by username and display name. The offset is the
+// number of results already returned, the returned offset is 0 on the last page.
+func (ps *ProfileService) SearchProfiles(query string, offset, limit int) ([]models.Profile, int, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, 0, errors.New("search query is required")
+	}
+	if runes := []rune(query); len(runes) > MaxSearchQueryLength {
+		query = string(runes[:MaxSearchQueryLength])
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	limit = normalizeLimit(limit)
+	profiles, err := ps.UserRepository.SearchProfiles(query, offset, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(profiles) <= limit {
+		return profiles, 0, nil
+	}
+	return profiles[:limit], offset + limit, nil
+}
+
+// UpdateProfile replaces the profile fields of a user, empty fields are cleared
+func (ps *ProfileService) UpdateProfile(userId int, request models.UpdateProfileRequest) (*models.Profile, error) {
+	request.DisplayName = strings.TrimSpace(request.DisplayName)
+	request.Bio = strings.TrimSpace(request.Bio)
+	request.Location = strings.TrimSpace(request.Location)
+	request.Website = strings.TrimSpace(request.Website)
+	if err := validateProfile(request); err != nil {
+		return nil, err
+	}
+	if err := ps.UserRepository.UpdateProfile(userId, request, userUpdatedMessages); err != nil {
+		return nil, err
+	}
+	return ps.UserRepository.GetProfile(userId)
+}
+
+func validateProfile(request models.UpdateProfileRequest) error {
+	if err := validateText("display name", request.DisplayName, MaxDisplayNameLength, false); err != nil {
+		return err
+	}
+	if err := validateText("bio", request.Bio, MaxBioLength, true); err != nil {
+		return err
+	}
+	if err := validateText("location", request.Location, MaxLocationLength, false); err != nil {
+		return err
+	}
+	if request.Website == "" {
+		return nil
+	}
+	if len(request.Website) > MaxWebsiteLength {
+		return errors.New("website is too long")
+	}
+	website, err := url.Parse(request.Website)
+	if err != nil || (website.Scheme != "http" && website.Scheme != "https") || website.Host == "" {
+		return errors.New("website should be an http or https url")
+	}
+	return nil
+}
+
+// validateText checks the length in characters of a profile field and rejects
+// control characters, newlines are only allowed when multiline is set
+func validateText(field, value string, maxLength int, multiline bool) error {
+	if !utf8.ValidString(value) {
+		return errors.New(field + " is not valid utf-8")
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return errors.New(field + " is too long")
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) && !(multiline && r == '\n') {
+			return errors.New(field + " contains invalid characters")
+		}
+	}
+	return nil
+}
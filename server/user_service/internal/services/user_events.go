@@ -0,0 +1,34 @@
+package services
+
+import (
+	"shared/eventbus"
+	"shared/outbox"
+	"time"
+	"user_service/internal/amqp"
+	"user_service/internal/models"
+)
+
+// EventSource identifies user_service in the envelopes it publishes
+const EventSource = "user_service"
+
+// newOutboxMessage wraps an event in an envelope to be saved in the outbox and published to topic
+func newOutboxMessage(topic string, event eventbus.Event) (outbox.Message, error) {
+	return outbox.NewMessage(EventSource, topic, event)
+}
+
+// userUpdatedMessages announces the current public data of a user, to be saved
+// in the outbox in the transaction that changed it
+func userUpdatedMessages(profile *models.Profile) ([]outbox.Message, error) {
+	message, err := newOutboxMessage(amqp.UserEventsExchange, &models.UserEvent{
+		Type:          models.UserUpdated,
+		UserId:        profile.Id,
+		Username:      profile.Username,
+		DisplayName:   profile.DisplayName,
+		AvatarVersion: profile.AvatarVersion,
+		OccurredAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []outbox.Message{message}, nil
+}
@@ -2,126 +2,291 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"shared/eventbus"
+	"shared/outbox"
+	"shared/photo"
+	"slices"
 	"time"
 	"user_service/internal/amqp"
+	"user_service/internal/keys"
 	"user_service/internal/models"
 	"user_service/internal/repository"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/rabbitmq/amqp091-go"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserServiceIn interface {
-	Register(username, password string) (string, error)
-	SignIn(username, password string) (string, error)
+	Register(username, password string) (*models.TokenResponse, error)
+	SignIn(username, password string) (*models.TokenResponse, error)
+	Refresh(refreshToken string) (*models.TokenResponse, error)
+	Logout(token string) error
 	UpdateAvatar(newAvatar []byte, userId int) error
+	GetAvatar(userId, size int) (*models.AvatarDb, error)
+	HandleGeneratedAvatar(ctx context.Context, envelope *eventbus.Envelope) error
 	GetDataFromToken(token string) (int, string, error)
 }
 
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 type UserService struct {
-	UserRepository repository.UserRepositoryIn
-	JwtSecretKey   string
-	Amqp           *amqp.Amqp
+	UserRepository    repository.UserRepositoryIn
+	SessionRepository repository.SessionRepositoryIn
+	Keys              *keys.KeySet
+	AccessTokenTTL    time.Duration
+	RefreshTokenTTL   time.Duration
+	Publisher         eventbus.Publisher
+}
+
+type accessClaims struct {
+	UserId    int    `json:"userId"`
+	Username  string `json:"username"`
+	SessionId string `json:"sid"`
+	jwt.RegisteredClaims
 }
 
 var _ UserServiceIn = &UserService{}
 
-func (us *UserService) Register(username, password string) (string, error) {
+func (us *UserService) Register(username, password string) (*models.TokenResponse, error) {
 	if err := us.validate(username, password); err != nil {
-		return "", err
+		return nil, err
 	}
 	candidate := us.UserRepository.FindUserByUsername(username)
 	if candidate != nil {
-		return "", errors.New("user with that username already exists")
+		return nil, errors.New("user with that username already exists")
 	}
 	hashBytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	hashedPassword := string(hashBytes)
-	userId, err := us.UserRepository.CreateUser(username, hashedPassword)
+	userId, err := us.UserRepository.CreateUser(username, hashedPassword, func(userId int) ([]outbox.Message, error) {
+		return registrationMessages(userId, username)
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	message := models.CreatePhotoMessage{UserId: userId, Username: username}
-	bytesJson, err := json.Marshal(&message)
-	if err != nil {
-		return "", err
-	}
-	err = us.Amqp.Channel.PublishWithContext(ctx,
-		"",
-		us.Amqp.Queue.Name,
-		false,
-		false,
-		amqp091.Publishing{
-			ContentType: "text/plain",
-			Body:        bytesJson,
-		},
-	)
-	if err != nil {
-		return "", err
-	}
+	return us.createSession(userId, username)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId":    userId,
-		"username":  username,
-		"timestamp": time.Now().Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(us.JwtSecretKey))
-	if err != nil {
-		return "", err
+// registrationMessages are published once a user is registered: photo_service
+// generates the default avatar and the other services learn about the user
+func registrationMessages(userId int, username string) ([]outbox.Message, error) {
+	messages := []struct {
+		topic string
+		event eventbus.Event
+	}{
+		{amqp.PhotoQueue, &photo.CreatePhotoMessage{UserId: userId, Username: username}},
+		{amqp.UserEventsExchange, &models.UserEvent{
+			Type:       models.UserCreated,
+			UserId:     userId,
+			Username:   username,
+			OccurredAt: time.Now().UTC(),
+		}},
+	}
+	outboxMessages := make([]outbox.Message, 0, len(messages))
+	for _, message := range messages {
+		outboxMessage, err := newOutboxMessage(message.topic, message.event)
+		if err != nil {
+			return nil, err
+		}
+		outboxMessages = append(outboxMessages, outboxMessage)
 	}
-	return tokenString, nil
+	return outboxMessages, nil
 }
 
-func (us *UserService) SignIn(username, password string) (string, error) {
+func (us *UserService) SignIn(username, password string) (*models.TokenResponse, error) {
 	if err := us.validate(username, password); err != nil {
-		return "", err
+		return nil, err
 	}
 	candidate := us.UserRepository.FindUserByUsername(username)
 	if candidate == nil {
-		return "", errors.New("invalid username or password")
+		return nil, errors.New("invalid username or password")
 	}
 	err := bcrypt.CompareHashAndPassword([]byte(candidate.Password),
 		[]byte(password))
 	if err != nil {
-		return "", errors.New("invalid username or password")
+		return nil, errors.New("invalid username or password")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"userId":    candidate.Id,
-		"username":  username,
-		"timestamp": time.Now().Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(us.JwtSecretKey))
+	return us.createSession(candidate.Id, candidate.Username)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Every refresh token
+// can be used only once, reusing one revokes the whole session.
+func (us *UserService) Refresh(refreshToken string) (*models.TokenResponse, error) {
+	newRefreshToken, err := randomToken()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return tokenString, nil
+	session, err := us.SessionRepository.RotateRefreshToken(
+		hashToken(refreshToken), hashToken(newRefreshToken), time.Now().Add(us.refreshTokenTTL()))
+	if err != nil {
+		return nil, err
+	}
+	return us.issueTokens(session.Id, session.UserId, session.Username, newRefreshToken)
 }
 
+// Logout revokes the session the access token belongs to, together with its refresh tokens
+func (us *UserService) Logout(token string) error {
+	claims, err := us.parseToken(token)
+	if err != nil {
+		return err
+	}
+	return us.SessionRepository.RevokeSession(claims.SessionId)
+}
+
+// AvatarSizes are the sizes photo_service renders every avatar in
+var AvatarSizes = []int{32, 64, 200, 512}
+
+const DefaultAvatarSize = 200
+
+// UpdateAvatar hands an uploaded avatar over to photo_service, which crops it and
+// stores it in every size. The new avatar is visible once it is processed.
 func (us *UserService) UpdateAvatar(newAvatar []byte, userId int) error {
-	err := us.UserRepository.UpdateAvatar(newAvatar, userId)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	envelope, err := eventbus.NewEnvelope(EventSource, &photo.ProcessAvatarMessage{UserId: userId, Data: newAvatar})
 	if err != nil {
 		return err
 	}
+	return us.Publisher.Publish(ctx, amqp.AvatarProcessQueue, envelope)
+}
+
+// HandleGeneratedAvatar stores the avatar sizes photo_service rendered and lets
+// the other services know the avatar version changed
+func (us *UserService) HandleGeneratedAvatar(ctx context.Context, envelope *eventbus.Envelope) error {
+	const op = "services.HandleGeneratedAvatar"
+
+	var message photo.AvatarGeneratedMessage
+	if err := envelope.Decode(&message); err != nil {
+		return eventbus.Permanent(fmt.Errorf("%s: %w", op, err))
+	}
+	for _, size := range AvatarSizes {
+		if len(message.Avatars[size]) == 0 {
+			return eventbus.Permanent(fmt.Errorf("%s: avatar of user %d is missing size %d", op, message.UserId, size))
+		}
+	}
+	if err := us.UserRepository.SaveAvatars(message.UserId, message.Avatars, userUpdatedMessages); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	return nil
 }
 
+func (us *UserService) GetAvatar(userId, size int) (*models.AvatarDb, error) {
+	if !slices.Contains(AvatarSizes, size) {
+		return nil, fmt.Errorf("unsupported avatar size %d", size)
+	}
+	return us.UserRepository.GetAvatar(userId, size)
+}
+
+// GetDataFromToken validates an access token and returns the user it was issued to.
+// Expired tokens and tokens of revoked sessions are rejected.
 func (us *UserService) GetDataFromToken(token string) (int, string, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(us.JwtSecretKey), nil
-	})
+	claims, err := us.parseToken(token)
 	if err != nil {
 		return 0, "", err
 	}
-	return int(claims["userId"].(float64)), claims["username"].(string), nil
+	active, err := us.SessionRepository.IsSessionActive(claims.SessionId)
+	if err != nil {
+		return 0, "", err
+	}
+	if !active {
+		return 0, "", errors.New("session has been revoked")
+	}
+	return claims.UserId, claims.Username, nil
+}
+
+func (us *UserService) createSession(userId int, username string) (*models.TokenResponse, error) {
+	sessionId, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	err = us.SessionRepository.CreateSession(
+		sessionId, userId, hashToken(refreshToken), time.Now().Add(us.refreshTokenTTL()))
+	if err != nil {
+		return nil, err
+	}
+	return us.issueTokens(sessionId, userId, username, refreshToken)
+}
+
+func (us *UserService) issueTokens(sessionId string, userId int, username, refreshToken string) (*models.TokenResponse, error) {
+	jti, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tokenString, err := us.Keys.Sign(accessClaims{
+		UserId:    userId,
+		Username:  username,
+		SessionId: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(us.accessTokenTTL())),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.TokenResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(us.accessTokenTTL().Seconds()),
+	}, nil
+}
+
+func (us *UserService) parseToken(token string) (*accessClaims, error) {
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, us.Keys.Keyfunc,
+		jwt.WithValidMethods(us.Keys.Methods()), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionId == "" {
+		return nil, errors.New("token is not bound to a session")
+	}
+	return claims, nil
+}
+
+func (us *UserService) accessTokenTTL() time.Duration {
+	if us.AccessTokenTTL <= 0 {
+		return DefaultAccessTokenTTL
+	}
+	return us.AccessTokenTTL
+}
+
+func (us *UserService) refreshTokenTTL() time.Duration {
+	if us.RefreshTokenTTL <= 0 {
+		return DefaultRefreshTokenTTL
+	}
+	return us.RefreshTokenTTL
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashToken is used to store refresh tokens, so a leaked table can't be used to refresh sessions
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 func (us *UserService) validate(username, password string) error {
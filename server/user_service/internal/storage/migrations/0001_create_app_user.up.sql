@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS app_user(
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(255) UNIQUE NOT NULL,
+    password TEXT NOT NULL,
+    avatar bytea
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_follow(
+    follower_id INTEGER NOT NULL REFERENCES app_user (id) ON DELETE CASCADE,
+    followee_id INTEGER NOT NULL REFERENCES app_user (id) ON DELETE CASCADE,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+CREATE INDEX IF NOT EXISTS user_follow_followee_idx ON user_follow (followee_id, follower_id);
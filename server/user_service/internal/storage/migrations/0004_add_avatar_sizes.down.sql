@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_avatar;
+ALTER TABLE app_user DROP COLUMN IF EXISTS avatar_version;
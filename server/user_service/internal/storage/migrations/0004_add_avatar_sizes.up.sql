@@ -0,0 +1,7 @@
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS avatar_version INTEGER NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS user_avatar(
+    user_id INTEGER NOT NULL REFERENCES app_user (id) ON DELETE CASCADE,
+    size INTEGER NOT NULL,
+    data bytea NOT NULL,
+    PRIMARY KEY (user_id, size)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE app_user DROP COLUMN IF EXISTS created_at;
+ALTER TABLE app_user DROP COLUMN IF EXISTS legacy;
+ALTER TABLE app_user DROP COLUMN IF EXISTS website;
+ALTER TABLE app_user DROP COLUMN IF EXISTS location;
+ALTER TABLE app_user DROP COLUMN IF EXISTS bio;
+ALTER TABLE app_user DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,10 @@
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS display_name VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS bio VARCHAR(280) NOT NULL DEFAULT '';
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS location VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS website VARCHAR(255) NOT NULL DEFAULT '';
+-- Users who signed up before created_at was added get the time this migration runs,
+-- which says nothing about them. legacy flags them, it is true for the users that
+-- exist now and false for everyone who signs up from here on.
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS legacy BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE app_user ALTER COLUMN legacy SET DEFAULT false;
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
@@ -0,0 +1,16 @@
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS exchange VARCHAR(255);
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS routing_key VARCHAR(255);
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'outbox' AND column_name = 'topic') THEN
+        UPDATE outbox SET
+            exchange = CASE WHEN topic = 'user_events' THEN topic ELSE '' END,
+            routing_key = CASE WHEN topic = 'user_events' THEN message_type ELSE topic END;
+        UPDATE outbox SET payload = convert_to((convert_from(payload, 'UTF8')::json -> 'data')::text, 'UTF8')
+        WHERE sent_at IS NULL;
+        UPDATE outbox SET message_type = 'CreatePhotoMessage' WHERE message_type = 'avatar.create_default';
+        ALTER TABLE outbox DROP COLUMN topic;
+    END IF;
+END $$;
+ALTER TABLE outbox ALTER COLUMN exchange SET NOT NULL;
+ALTER TABLE outbox ALTER COLUMN routing_key SET NOT NULL;
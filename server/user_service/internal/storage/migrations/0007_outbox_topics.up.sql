@@ -0,0 +1,22 @@
+-- Outbox messages are event bus envelopes published to a topic
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS topic VARCHAR(255);
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'outbox' AND column_name = 'exchange') THEN
+        UPDATE outbox SET message_type = 'avatar.create_default' WHERE message_type = 'CreatePhotoMessage';
+        -- Wrap the pending messages written before envelopes existed
+        UPDATE outbox SET payload = convert_to(json_build_object(
+                'id', md5('outbox-' || id),
+                'type', message_type,
+                'version', 1,
+                'source', 'user_service',
+                'occurredAt', created_at,
+                'data', convert_from(payload, 'UTF8')::json
+            )::text, 'UTF8')
+        WHERE sent_at IS NULL;
+        UPDATE outbox SET topic = CASE WHEN exchange = '' THEN routing_key ELSE exchange END;
+        ALTER TABLE outbox DROP COLUMN exchange;
+        ALTER TABLE outbox DROP COLUMN routing_key;
+    END IF;
+END $$;
+ALTER TABLE outbox ALTER COLUMN topic SET NOT NULL;
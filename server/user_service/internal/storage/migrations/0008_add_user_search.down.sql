@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS app_user_display_name_trgm_idx;
+DROP INDEX IF EXISTS app_user_username_trgm_idx;
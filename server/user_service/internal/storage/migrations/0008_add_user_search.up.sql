@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+-- Trigram indexes serve both the prefix (LIKE 'abc%') and the fuzzy (%) matches of user search
+CREATE INDEX IF NOT EXISTS app_user_username_trgm_idx ON app_user USING GIN (lower(username) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS app_user_display_name_trgm_idx ON app_user USING GIN (lower(display_name) gin_trgm_ops);
@@ -0,0 +1,5 @@
+-- updated_at is set whenever the profile or the avatar of a user changes
+ALTER TABLE app_user ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+UPDATE app_user SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE app_user ALTER COLUMN updated_at SET DEFAULT now();
+ALTER TABLE app_user ALTER COLUMN updated_at SET NOT NULL;
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"context"
+	"embed"
 	"fmt"
 	"os"
+	"shared/migrate"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
@@ -12,16 +15,24 @@ type Storage struct {
 	Db *sqlx.DB
 }
 
-const schema = `
-CREATE TABLE IF NOT EXISTS app_user(
-    id SERIAL PRIMARY KEY,
-    username VARCHAR(255) UNIQUE NOT NULL,
-    password TEXT NOT NULL,
-	avatar bytea
-)
-`
+//go:embed migrations/*.sql
+var migrations embed.FS
 
+// New connects to the database and brings its schema up to date
 func New() *Storage {
+	s := Open()
+	migrator, err := s.Migrator()
+	if err != nil {
+		logrus.Fatalln(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		logrus.Fatalln(err)
+	}
+	return s
+}
+
+// Open connects to the database without touching its schema
+func Open() *Storage {
 	connectionString := fmt.Sprintf(
 		"user=%v password=%v dbname=%v port=%v host=host.docker.internal sslmode=disable",
 		os.Getenv("DB_USERNAME"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), os.Getenv("DB_PORT"),
@@ -30,11 +41,15 @@ func New() *Storage {
 	if err != nil {
 		logrus.Fatalln(err)
 	}
-	db.MustExec(schema)
 
 	return &Storage{Db: db}
 }
 
+// Migrator loads the embedded migrations of the service
+func (s *Storage) Migrator() (*migrate.Migrator, error) {
+	return migrate.New(s.Db, migrations, "migrations", "user_service")
+}
+
 func (s *Storage) Stop() {
 	const op = "storage.Stop"
 	logrus.WithField("op", op).Info("closing database instance")